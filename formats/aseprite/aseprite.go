@@ -0,0 +1,212 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package aseprite reads the native files of the Aseprite editor (".aseprite"
+// and ".ase").
+//
+// All three color modes are decoded, but the package is mainly meant for
+// indexed sprites: the frames of indexed files are flattened into paletted
+// images that keep the original indices. Tilemap layers and user data are
+// skipped.
+package aseprite
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"os"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A File is the content of an Aseprite file.
+type File struct {
+	Width, Height int
+	ColorDepth    int   // 8 (indexed), 16 (grayscale) or 32 (RGBA)
+	Transparent   uint8 // index of the transparent color (indexed mode only)
+	Palette       color.Palette
+	Layers        []Layer
+	Frames        []Frame
+	Tags          []Tag
+	Slices        []Slice
+}
+
+// Color depths.
+const (
+	Indexed   = 8
+	Grayscale = 16
+	RGBA      = 32
+)
+
+// A Layer is either a layer of images or a group of layers. The layers are
+// listed from bottom to top, each group before its children.
+type Layer struct {
+	Name       string
+	Visible    bool
+	Background bool
+	Type       LayerType
+	ChildLevel int // 0 for top-level layers, 1 for their children, and so on
+	Parent     int // index of the enclosing group, or -1
+	BlendMode  int // only normal blending is used when flattening
+	Opacity    uint8
+}
+
+// A LayerType is the kind of a layer.
+type LayerType uint16
+
+// Types of layers.
+const (
+	Normal  LayerType = 0
+	Group   LayerType = 1
+	Tilemap LayerType = 2
+)
+
+// A Frame is a step of the animation of the sprite.
+type Frame struct {
+	Duration int // in milliseconds
+	Cels     []Cel
+}
+
+// A Cel is the image of a layer in a frame.
+type Cel struct {
+	Layer   int // index in the layers of the file
+	X, Y    int // position in the sprite
+	Opacity uint8
+	ZIndex  int         // offset of the cel in the stack of layers
+	Image   image.Image // *image.Paletted in indexed mode, *image.NRGBA otherwise
+	Link    int         // frame of the original cel for linked cels, or -1
+}
+
+// A Tag is a named range of frames.
+type Tag struct {
+	Name      string
+	From, To  int // frame range (both included)
+	Direction Direction
+	Repeat    int // number of times the range is played (0 for infinite)
+}
+
+// A Direction specifies how the frames of a tag are played.
+type Direction uint8
+
+// Directions of tags.
+const (
+	Forward Direction = iota
+	Reverse
+	PingPong
+	PingPongReverse
+)
+
+// A Slice is a named region of the sprite, which can change over the frames.
+type Slice struct {
+	Name string
+	Keys []SliceKey
+}
+
+// A SliceKey gives the properties of a slice, starting from a frame.
+type SliceKey struct {
+	Frame  int
+	Bounds image.Rectangle
+	// Center is the center of the nine-patch, relative to Bounds (empty if the
+	// slice is not a nine-patch).
+	Center image.Rectangle
+	// Pivot is the pivot point, relative to Bounds (only if HasPivot is true).
+	Pivot    image.Point
+	HasPivot bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Read decodes an Aseprite file.
+func Read(r io.Reader) (*File, error) {
+	var b bytes.Buffer
+	_, err := b.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	return decode(b.Bytes())
+}
+
+// Open reads the Aseprite file at the given path.
+func Open(name string) (*File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Layer returns the index of the first layer with the given name, or -1.
+func (f *File) Layer(name string) int {
+	for i := range f.Layers {
+		if f.Layers[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Tag returns the first tag with the given name, or nil.
+func (f *File) Tag(name string) *Tag {
+	for i := range f.Tags {
+		if f.Tags[i].Name == name {
+			return &f.Tags[i]
+		}
+	}
+	return nil
+}
+
+// Slice returns the first slice with the given name, or nil.
+func (f *File) Slice(name string) *Slice {
+	for i := range f.Slices {
+		if f.Slices[i].Name == name {
+			return &f.Slices[i]
+		}
+	}
+	return nil
+}
+
+// At returns the key in effect at a frame, or nil if the slice does not exist
+// yet.
+func (s *Slice) At(frame int) *SliceKey {
+	var k *SliceKey
+	for i := range s.Keys {
+		if s.Keys[i].Frame <= frame {
+			k = &s.Keys[i]
+		}
+	}
+	return k
+}
+
+// IsVisible returns true if the layer and all its enclosing groups are
+// visible.
+func (f *File) IsVisible(layer int) bool {
+	for l := layer; l >= 0; l = f.Layers[l].Parent {
+		if !f.Layers[l].Visible {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Image returns a frame of the sprite, with all visible layers flattened.
+//
+// In indexed mode the result is an *image.Paletted, where the transparent
+// color of each layer (except the background) lets the layers below show
+// through; opacities and blend modes are ignored, as in Aseprite. In the other
+// modes, the result is an *image.NRGBA, and the layers are blended with their
+// opacity (but always in normal mode).
+func (f *File) Image(frame int) image.Image {
+	return f.flatten(frame, func(l int) bool { return f.IsVisible(l) })
+}
+
+// LayerImage returns the image of a single layer in a frame (in the same format
+// as Image), regardless of its visibility.
+func (f *File) LayerImage(frame, layer int) image.Image {
+	return f.flatten(frame, func(l int) bool { return l == layer })
+}
@@ -0,0 +1,504 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"sort"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Magic numbers and chunk types of the file format.
+const (
+	fileMagic  = 0xA5E0
+	frameMagic = 0xF1FA
+
+	chunkOldPalette = 0x0004
+	chunkLayer      = 0x2004
+	chunkCel        = 0x2005
+	chunkTags       = 0x2018
+	chunkPalette    = 0x2019
+	chunkSlice      = 0x2022
+)
+
+var errEOF = errors.New("aseprite: unexpected end of data")
+
+func decode(b []byte) (*File, error) {
+	r := &reader{b: b}
+
+	r.dword() // file size
+	if r.word() != fileMagic {
+		return nil, errors.New("aseprite: not an Aseprite file")
+	}
+	nf := r.word()
+	f := &File{
+		Width:      r.word(),
+		Height:     r.word(),
+		ColorDepth: r.word(),
+	}
+	flags := r.dword()
+	r.skip(2 + 4 + 4) // speed and reserved
+	f.Transparent = uint8(r.byte())
+	r.skip(3)
+	nc := r.word()
+	r.skip(2 + 4 + 4 + 84) // pixel ratio, grid and reserved
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch f.ColorDepth {
+	case Indexed, Grayscale, RGBA:
+	default:
+		return nil, errors.New("aseprite: unsupported color depth " + strconv.Itoa(f.ColorDepth))
+	}
+	if nc == 0 {
+		nc = 256
+	}
+	f.Palette = make(color.Palette, nc)
+	for i := range f.Palette {
+		f.Palette[i] = color.NRGBA{}
+	}
+
+	d := decoder{
+		File:         f,
+		layerOpacity: flags&1 != 0,
+	}
+	for i := 0; i < nf; i++ {
+		err := d.frame(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The palette is only known at the end
+	p := f.imagePalette()
+	for _, fr := range f.Frames {
+		for _, c := range fr.Cels {
+			if m, ok := c.Image.(*image.Paletted); ok {
+				m.Palette = p
+			}
+		}
+	}
+
+	return f, nil
+}
+
+type decoder struct {
+	*File
+	layerOpacity bool
+	newPalette   bool  // true once a palette chunk has been seen
+	levels       []int // last layer seen at each child level
+}
+
+func (d *decoder) frame(r *reader) error {
+	start := r.pos
+	size := r.dword()
+	if r.word() != frameMagic {
+		if r.err != nil {
+			return r.err
+		}
+		return errors.New("aseprite: invalid frame header")
+	}
+	n := r.word()
+	fr := Frame{Duration: r.word()}
+	r.skip(2)
+	if m := r.dword(); m != 0 {
+		n = m
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	for j := 0; j < n; j++ {
+		sz := r.dword()
+		typ := r.word()
+		c := r.sub(sz - 6)
+		if r.err != nil {
+			return r.err
+		}
+		var err error
+		switch typ {
+		case chunkOldPalette:
+			d.oldPalette(c)
+		case chunkPalette:
+			d.palette(c)
+		case chunkLayer:
+			d.layer(c)
+		case chunkCel:
+			err = d.cel(c, &fr)
+		case chunkTags:
+			d.tags(c)
+		case chunkSlice:
+			d.slice(c)
+		}
+		if err != nil {
+			return err
+		}
+		if c.err != nil {
+			return c.err
+		}
+	}
+
+	d.Frames = append(d.Frames, fr)
+	r.pos = start + size
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (d *decoder) oldPalette(r *reader) {
+	if d.newPalette {
+		return
+	}
+	np := r.word()
+	i := 0
+	for k := 0; k < np && r.err == nil; k++ {
+		i += r.byte()
+		n := r.byte()
+		if n == 0 {
+			n = 256
+		}
+		for ; n > 0; n-- {
+			c := color.NRGBA{uint8(r.byte()), uint8(r.byte()), uint8(r.byte()), 0xFF}
+			d.setColor(i, c)
+			i++
+		}
+	}
+}
+
+func (d *decoder) palette(r *reader) {
+	d.newPalette = true
+	size := r.dword()
+	first, last := r.dword(), r.dword()
+	r.skip(8)
+	if r.err != nil || size > 256 {
+		return
+	}
+	for len(d.Palette) < size {
+		d.Palette = append(d.Palette, color.NRGBA{})
+	}
+	d.Palette = d.Palette[:size]
+	for i := first; i <= last && r.err == nil; i++ {
+		flags := r.word()
+		c := color.NRGBA{uint8(r.byte()), uint8(r.byte()), uint8(r.byte()), uint8(r.byte())}
+		if flags&1 != 0 {
+			r.str() // name
+		}
+		d.setColor(i, c)
+	}
+}
+
+func (d *decoder) setColor(i int, c color.NRGBA) {
+	if i < 0 || i >= 256 {
+		return
+	}
+	for len(d.Palette) <= i {
+		d.Palette = append(d.Palette, color.NRGBA{})
+	}
+	d.Palette[i] = c
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (d *decoder) layer(r *reader) {
+	flags := r.word()
+	l := Layer{
+		Visible:    flags&1 != 0,
+		Background: flags&8 != 0,
+		Type:       LayerType(r.word()),
+		ChildLevel: r.word(),
+	}
+	r.skip(4) // default width and height
+	l.BlendMode = r.word()
+	l.Opacity = uint8(r.byte())
+	if !d.layerOpacity {
+		l.Opacity = 0xFF
+	}
+	r.skip(3)
+	l.Name = r.str()
+
+	l.Parent = -1
+	if l.ChildLevel > 0 && l.ChildLevel <= len(d.levels) {
+		l.Parent = d.levels[l.ChildLevel-1]
+	}
+	if l.ChildLevel < len(d.levels) {
+		d.levels = d.levels[:l.ChildLevel]
+	}
+	d.levels = append(d.levels, len(d.Layers))
+
+	d.Layers = append(d.Layers, l)
+}
+
+func (d *decoder) cel(r *reader, fr *Frame) error {
+	c := Cel{
+		Layer:   r.word(),
+		X:       r.short(),
+		Y:       r.short(),
+		Opacity: uint8(r.byte()),
+		Link:    -1,
+	}
+	typ := r.word()
+	c.ZIndex = r.short()
+	r.skip(5)
+	if r.err != nil {
+		return r.err
+	}
+
+	switch typ {
+	case 0, 2:
+		w, h := r.word(), r.word()
+		n := w * h * (d.ColorDepth / 8)
+		var pix []byte
+		if typ == 0 {
+			pix = append([]byte(nil), r.bytes(n)...)
+		} else {
+			z, err := zlib.NewReader(bytes.NewReader(r.rest()))
+			if err != nil {
+				return errors.New("aseprite: invalid compressed cel: " + err.Error())
+			}
+			pix = make([]byte, n)
+			_, err = io.ReadFull(z, pix)
+			if err != nil {
+				return errors.New("aseprite: invalid compressed cel: " + err.Error())
+			}
+		}
+		if r.err != nil {
+			return r.err
+		}
+		c.Image = d.image(w, h, pix)
+
+	case 1:
+		l := r.word()
+		if l >= len(d.Frames) {
+			return errors.New("aseprite: invalid linked cel")
+		}
+		found := false
+		for _, o := range d.Frames[l].Cels {
+			if o.Layer == c.Layer {
+				c.X, c.Y, c.Opacity, c.Image = o.X, o.Y, o.Opacity, o.Image
+				c.Link = l
+				found = true
+			}
+		}
+		if !found {
+			return errors.New("aseprite: invalid linked cel")
+		}
+
+	default:
+		// Tilemaps are not supported
+		return nil
+	}
+
+	fr.Cels = append(fr.Cels, c)
+	return nil
+}
+
+// image creates the image of a cel from its pixels.
+func (d *decoder) image(w, h int, pix []byte) image.Image {
+	r := image.Rect(0, 0, w, h)
+	switch d.ColorDepth {
+	case Indexed:
+		return &image.Paletted{Pix: pix, Stride: w, Rect: r}
+	case Grayscale:
+		m := image.NewNRGBA(r)
+		for i := 0; i < w*h; i++ {
+			v, a := pix[2*i], pix[2*i+1]
+			copy(m.Pix[4*i:], []byte{v, v, v, a})
+		}
+		return m
+	default:
+		return &image.NRGBA{Pix: pix, Stride: 4 * w, Rect: r}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (d *decoder) tags(r *reader) {
+	n := r.word()
+	r.skip(8)
+	for i := 0; i < n && r.err == nil; i++ {
+		t := Tag{
+			From:      r.word(),
+			To:        r.word(),
+			Direction: Direction(r.byte()),
+			Repeat:    r.word(),
+		}
+		r.skip(6 + 3 + 1) // reserved and color
+		t.Name = r.str()
+		d.Tags = append(d.Tags, t)
+	}
+}
+
+func (d *decoder) slice(r *reader) {
+	n := r.dword()
+	flags := r.dword()
+	r.skip(4)
+	s := Slice{Name: r.str()}
+	for i := 0; i < n && r.err == nil; i++ {
+		k := SliceKey{Frame: r.dword()}
+		x, y := r.long(), r.long()
+		w, h := r.dword(), r.dword()
+		k.Bounds = image.Rect(x, y, x+w, y+h)
+		if flags&1 != 0 {
+			x, y := r.long(), r.long()
+			w, h := r.dword(), r.dword()
+			k.Center = image.Rect(x, y, x+w, y+h)
+		}
+		if flags&2 != 0 {
+			k.Pivot = image.Pt(r.long(), r.long())
+			k.HasPivot = true
+		}
+		s.Keys = append(s.Keys, k)
+	}
+	d.Slices = append(d.Slices, s)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// imagePalette returns the palette used for the paletted images: it always has
+// 256 colors, and the transparent index is transparent (unless the sprite has a
+// background layer).
+func (f *File) imagePalette() color.Palette {
+	p := make(color.Palette, 256)
+	for i := range p {
+		p[i] = color.NRGBA{}
+		if i < len(f.Palette) {
+			p[i] = f.Palette[i]
+		}
+	}
+	for _, l := range f.Layers {
+		if l.Background {
+			return p
+		}
+	}
+	p[f.Transparent] = color.NRGBA{}
+	return p
+}
+
+// flatten composes the cels of a frame, for the layers selected by include.
+func (f *File) flatten(frame int, include func(layer int) bool) image.Image {
+	b := image.Rect(0, 0, f.Width, f.Height)
+
+	var cels []Cel
+	for _, c := range f.Frames[frame].Cels {
+		if c.Layer < len(f.Layers) && f.Layers[c.Layer].Type == Normal && include(c.Layer) {
+			cels = append(cels, c)
+		}
+	}
+	// Same order as Aseprite: the z-index moves the cel in the stack of layers,
+	// and wins over the layer order in case of tie
+	sort.SliceStable(cels, func(i, j int) bool {
+		a, b := cels[i], cels[j]
+		if a.Layer+a.ZIndex != b.Layer+b.ZIndex {
+			return a.Layer+a.ZIndex < b.Layer+b.ZIndex
+		}
+		return a.ZIndex < b.ZIndex
+	})
+
+	if f.ColorDepth == Indexed {
+		m := image.NewPaletted(b, f.imagePalette())
+		for i := range m.Pix {
+			m.Pix[i] = f.Transparent
+		}
+		for _, c := range cels {
+			s := c.Image.(*image.Paletted)
+			bg := f.Layers[c.Layer].Background
+			r := s.Rect.Add(image.Pt(c.X, c.Y)).Intersect(b)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					v := s.Pix[s.PixOffset(x-c.X, y-c.Y)]
+					if v != f.Transparent || bg {
+						m.Pix[m.PixOffset(x, y)] = v
+					}
+				}
+			}
+		}
+		return m
+	}
+
+	m := image.NewNRGBA(b)
+	for _, c := range cels {
+		a := uint32(c.Opacity) * uint32(f.Layers[c.Layer].Opacity) / 0xFF
+		r := c.Image.Bounds().Add(image.Pt(c.X, c.Y))
+		draw.DrawMask(m, r, c.Image, image.Point{}, image.NewUniform(color.Alpha{uint8(a)}), image.Point{}, draw.Over)
+	}
+	return m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// reader decodes the little-endian values of the file format. After the first
+// error, all values are zero.
+type reader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.b) {
+		r.err = errEOF
+		return nil
+	}
+	v := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return v
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+// sub returns a reader for the next n bytes.
+func (r *reader) sub(n int) *reader {
+	return &reader{b: r.bytes(n)}
+}
+
+// rest returns all the remaining bytes.
+func (r *reader) rest() []byte {
+	return r.bytes(len(r.b) - r.pos)
+}
+
+func (r *reader) byte() int {
+	v := r.bytes(1)
+	if v == nil {
+		return 0
+	}
+	return int(v[0])
+}
+
+func (r *reader) word() int {
+	v := r.bytes(2)
+	if v == nil {
+		return 0
+	}
+	return int(v[0]) | int(v[1])<<8
+}
+
+func (r *reader) short() int {
+	return int(int16(r.word()))
+}
+
+func (r *reader) dword() int {
+	v := r.bytes(4)
+	if v == nil {
+		return 0
+	}
+	return int(uint32(v[0]) | uint32(v[1])<<8 | uint32(v[2])<<16 | uint32(v[3])<<24)
+}
+
+func (r *reader) long() int {
+	return int(int32(r.dword()))
+}
+
+func (r *reader) str() string {
+	return string(r.bytes(r.word()))
+}
@@ -0,0 +1,86 @@
+package aseprite_test
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/cozely/cozely/formats/aseprite"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOpen(t *testing.T) {
+	f, err := aseprite.Open("testdata/sprite.aseprite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Width != 4 || f.Height != 3 || f.ColorDepth != aseprite.Indexed || len(f.Frames) != 2 {
+		t.Errorf("wrong header: %dx%d, depth %d, %d frames", f.Width, f.Height, f.ColorDepth, len(f.Frames))
+	}
+	if len(f.Palette) != 4 || f.Palette[1] != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("wrong palette: %v", f.Palette)
+	}
+	if f.Frames[0].Duration != 100 || f.Frames[1].Duration != 200 {
+		t.Errorf("wrong durations: %d and %d", f.Frames[0].Duration, f.Frames[1].Duration)
+	}
+
+	if len(f.Layers) != 4 || f.Layer("fg") != 2 {
+		t.Fatalf("wrong layers: %+v", f.Layers)
+	}
+	if l := f.Layers[2]; l.Parent != 1 || l.ChildLevel != 1 || f.Layers[1].Type != aseprite.Group {
+		t.Errorf("wrong layer hierarchy: %+v", f.Layers)
+	}
+	if !f.Layers[0].Background || !f.IsVisible(2) || f.IsVisible(3) {
+		t.Errorf("wrong layer flags: %+v", f.Layers)
+	}
+
+	if c := f.Frames[1].Cels[0]; c.Link != 0 || c.Image != f.Frames[0].Cels[0].Image {
+		t.Errorf("wrong linked cel: %+v", c)
+	}
+
+	want := []aseprite.Tag{
+		{Name: "blink", From: 0, To: 1, Direction: aseprite.PingPong},
+		{Name: "back", From: 0, To: 1, Direction: aseprite.Reverse, Repeat: 3},
+	}
+	if !reflect.DeepEqual(f.Tags, want) {
+		t.Errorf("wrong tags: %+v", f.Tags)
+	}
+
+	s := f.Slice("panel")
+	if s == nil || len(s.Keys) != 1 {
+		t.Fatalf("wrong slices: %+v", f.Slices)
+	}
+	k := s.At(1)
+	if k == nil || k.Bounds != image.Rect(0, 0, 4, 3) || k.Center != image.Rect(1, 1, 3, 2) ||
+		!k.HasPivot || k.Pivot != image.Pt(2, 2) {
+		t.Errorf("wrong slice key: %+v", k)
+	}
+}
+
+func TestImage(t *testing.T) {
+	f, err := aseprite.Open("testdata/sprite.aseprite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range [][]uint8{
+		{1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1},
+		{3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	} {
+		m, ok := f.Image(i).(*image.Paletted)
+		if !ok {
+			t.Fatalf("frame %d: not a paletted image", i)
+		}
+		if !reflect.DeepEqual(m.Pix, want) {
+			t.Errorf("frame %d: want %v, got %v", i, want, m.Pix)
+		}
+	}
+
+	m := f.LayerImage(0, f.Layer("hidden")).(*image.Paletted)
+	if !reflect.DeepEqual(m.Pix, []uint8{3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Errorf("hidden layer: got %v", m.Pix)
+	}
+}
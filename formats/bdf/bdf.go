@@ -0,0 +1,277 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package bdf reads bitmap fonts in the Glyph Bitmap Distribution Format
+// (".bdf" files) defined by Adobe, and used by X11.
+//
+// Only horizontal writing is supported (the metrics for vertical writing are
+// ignored).
+package bdf
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Font is the content of a BDF file.
+type Font struct {
+	Name        string
+	Size        int // in points
+	BoundingBox Box // union of the boxes of all glyphs
+	Properties  map[string]string
+	Ascent      int  // distance from the top of the line to the baseline
+	Descent     int  // distance from the baseline to the bottom of the line
+	DefaultChar rune // character displayed for missing ones (-1 if unspecified)
+	Glyphs      []Glyph
+}
+
+// A Box is the position and size of a glyph bitmap. The offsets are the
+// position of its bottom-left corner, relative to the origin of the glyph on
+// the baseline (with Y pointing up).
+type Box struct {
+	Width, Height    int
+	XOffset, YOffset int
+}
+
+// A Glyph is the bitmap and metrics of a character.
+type Glyph struct {
+	Name     string
+	Encoding rune // -1 for glyphs outside of the encoding of the font
+	DWidth   int  // horizontal distance to the origin of the next glyph
+	BBX      Box
+	// Bitmap has the size of BBX; the pixels of the glyph are opaque, and the
+	// others are transparent.
+	Bitmap *image.Alpha
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Read decodes a BDF file.
+func Read(r io.Reader) (*Font, error) {
+	f := &Font{
+		Properties:  map[string]string{},
+		DefaultChar: -1,
+	}
+	p := parser{s: bufio.NewScanner(r), font: f}
+	if err := p.parse(); err != nil {
+		return nil, errors.New("bdf: line " + strconv.Itoa(p.line) + ": " + err.Error())
+	}
+	return f, nil
+}
+
+// Open reads the BDF file at the given path.
+func Open(name string) (*Font, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Glyph returns the glyph of a character, or nil if it is not in the font.
+func (f *Font) Glyph(r rune) *Glyph {
+	for i := range f.Glyphs {
+		if f.Glyphs[i].Encoding == r {
+			return &f.Glyphs[i]
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type parser struct {
+	s    *bufio.Scanner
+	line int
+	font *Font
+	dw   int // default DWIDTH (declared at the font level)
+}
+
+// next returns the keyword and arguments of the next non-empty line.
+func (p *parser) next() (string, []string, bool) {
+	for p.s.Scan() {
+		p.line++
+		l := strings.TrimSpace(p.s.Text())
+		if l == "" {
+			continue
+		}
+		f := strings.Fields(l)
+		if f[0] == "COMMENT" {
+			continue
+		}
+		return f[0], f[1:], true
+	}
+	return "", nil, false
+}
+
+func (p *parser) parse() error {
+	k, _, ok := p.next()
+	if !ok || k != "STARTFONT" {
+		return errors.New("not a BDF file")
+	}
+
+	f := p.font
+	ascent, descent := false, false
+	for {
+		k, args, ok := p.next()
+		if !ok {
+			return errors.New("missing ENDFONT")
+		}
+		switch k {
+		case "FONT":
+			f.Name = strings.Join(args, " ")
+		case "SIZE":
+			v, err := ints(args, 1)
+			if err != nil {
+				return err
+			}
+			f.Size = v[0]
+		case "FONTBOUNDINGBOX":
+			v, err := ints(args, 4)
+			if err != nil {
+				return err
+			}
+			f.BoundingBox = Box{v[0], v[1], v[2], v[3]}
+		case "DWIDTH":
+			v, err := ints(args, 1)
+			if err != nil {
+				return err
+			}
+			p.dw = v[0]
+		case "STARTPROPERTIES":
+			if err := p.properties(); err != nil {
+				return err
+			}
+		case "STARTCHAR":
+			g, err := p.glyph(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			f.Glyphs = append(f.Glyphs, g)
+		case "ENDFONT":
+			if v, err := strconv.Atoi(f.Properties["FONT_ASCENT"]); err == nil {
+				f.Ascent, ascent = v, true
+			}
+			if v, err := strconv.Atoi(f.Properties["FONT_DESCENT"]); err == nil {
+				f.Descent, descent = v, true
+			}
+			if v, err := strconv.Atoi(f.Properties["DEFAULT_CHAR"]); err == nil {
+				f.DefaultChar = rune(v)
+			}
+			if !ascent {
+				f.Ascent = f.BoundingBox.Height + f.BoundingBox.YOffset
+			}
+			if !descent {
+				f.Descent = -f.BoundingBox.YOffset
+			}
+			return nil
+		}
+	}
+}
+
+func (p *parser) properties() error {
+	for {
+		if !p.s.Scan() {
+			return errors.New("missing ENDPROPERTIES")
+		}
+		p.line++
+		l := strings.TrimSpace(p.s.Text())
+		if l == "ENDPROPERTIES" {
+			return nil
+		}
+		kv := strings.SplitN(l, " ", 2)
+		k, v := kv[0], ""
+		if len(kv) > 1 {
+			v = strings.TrimSpace(kv[1])
+		}
+		if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+			v = strings.ReplaceAll(v[1:len(v)-1], `""`, `"`)
+		}
+		if k != "" {
+			p.font.Properties[k] = v
+		}
+	}
+}
+
+func (p *parser) glyph(name string) (Glyph, error) {
+	g := Glyph{Name: name, Encoding: -1, DWidth: p.dw}
+	for {
+		k, args, ok := p.next()
+		if !ok {
+			return g, errors.New("missing ENDCHAR")
+		}
+		switch k {
+		case "ENCODING":
+			v, err := ints(args, 1)
+			if err != nil {
+				return g, err
+			}
+			if v[0] >= 0 {
+				g.Encoding = rune(v[0])
+			}
+		case "DWIDTH":
+			v, err := ints(args, 1)
+			if err != nil {
+				return g, err
+			}
+			g.DWidth = v[0]
+		case "BBX":
+			v, err := ints(args, 4)
+			if err != nil {
+				return g, err
+			}
+			g.BBX = Box{v[0], v[1], v[2], v[3]}
+		case "BITMAP":
+			if g.BBX.Width < 0 || g.BBX.Height < 0 {
+				return g, errors.New("invalid BBX")
+			}
+			g.Bitmap = image.NewAlpha(image.Rect(0, 0, g.BBX.Width, g.BBX.Height))
+			for y := 0; y < g.BBX.Height; y++ {
+				k, _, ok := p.next()
+				if !ok {
+					return g, errors.New("missing bitmap rows")
+				}
+				row, err := hex.DecodeString(k)
+				if err != nil || len(row) < (g.BBX.Width+7)/8 {
+					return g, errors.New("invalid bitmap row")
+				}
+				for x := 0; x < g.BBX.Width; x++ {
+					if row[x/8]&(0x80>>uint(x%8)) != 0 {
+						g.Bitmap.SetAlpha(x, y, color.Alpha{0xFF})
+					}
+				}
+			}
+		case "ENDCHAR":
+			if g.Bitmap == nil {
+				g.Bitmap = image.NewAlpha(image.Rect(0, 0, g.BBX.Width, g.BBX.Height))
+			}
+			return g, nil
+		}
+	}
+}
+
+// ints converts the first n arguments of a line to integers.
+func ints(args []string, n int) ([]int, error) {
+	if len(args) < n {
+		return nil, errors.New("missing arguments")
+	}
+	v := make([]int, n)
+	for i := range v {
+		var err error
+		v[i], err = strconv.Atoi(args[i])
+		if err != nil {
+			return nil, errors.New("invalid number " + strconv.Quote(args[i]))
+		}
+	}
+	return v, nil
+}
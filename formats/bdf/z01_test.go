@@ -0,0 +1,51 @@
+package bdf_test
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/formats/bdf"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOpen(t *testing.T) {
+	f, err := bdf.Open("testdata/font.bdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Size != 8 || f.BoundingBox != (bdf.Box{4, 8, -1, -2}) {
+		t.Errorf("wrong font attributes: %+v", f)
+	}
+	if f.Ascent != 6 || f.Descent != 2 || f.DefaultChar != '?' {
+		t.Errorf("wrong metrics: ascent %d, descent %d, default %q", f.Ascent, f.Descent, f.DefaultChar)
+	}
+	if v := f.Properties["COPYRIGHT"]; v != `Public "domain"` {
+		t.Errorf("wrong string property: %q", v)
+	}
+	if len(f.Glyphs) != 3 {
+		t.Fatalf("wrong number of glyphs: %d", len(f.Glyphs))
+	}
+
+	g := f.Glyph('g')
+	if g == nil || g.Name != "g" || g.DWidth != 3 || g.BBX != (bdf.Box{3, 5, -1, -2}) {
+		t.Fatalf("wrong glyph: %+v", g)
+	}
+	rows := ""
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 3; x++ {
+			if g.Bitmap.AlphaAt(x, y).A != 0 {
+				rows += "#"
+			} else {
+				rows += "."
+			}
+		}
+		rows += "\n"
+	}
+	if want := ".##\n#.#\n.##\n..#\n##.\n"; rows != want {
+		t.Errorf("wrong bitmap:\n%s", rows)
+	}
+	if f.Glyph('z') != nil {
+		t.Errorf("missing glyph found")
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package bmfont reads the descriptions of bitmap fonts made with AngelCode's
+// BMFont (and the many tools using the same format), in both the text and the
+// binary versions of ".fnt" files.
+//
+// The glyphs themselves are stored in separate image files (the pages), which
+// are not read by this package.
+package bmfont
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Font is the content of a BMFont description file.
+type Font struct {
+	Face           string
+	Size           int // can be negative, meaning that the size is the character height
+	Bold, Italic   bool
+	Unicode        bool
+	Padding        [4]int // up, right, down, left
+	Spacing        [2]int // horizontal and vertical
+	LineHeight     int    // distance between two lines of text
+	Base           int    // distance from the top of the line to the baseline
+	ScaleW, ScaleH int    // size of the pages
+	Pages          []string
+	Chars          []Char
+	Kernings       []Kerning
+}
+
+// A Char describes the glyph of a character.
+type Char struct {
+	ID                  rune
+	X, Y, Width, Height int // rectangle of the glyph in its page
+	XOffset, YOffset    int // position of the glyph, relative to the cursor at the top of the line
+	XAdvance            int // distance between the cursor and the next one
+	Page                int
+	Channel             int // channels of the page containing the glyph (15 for all)
+}
+
+// A Kerning adjusts the distance between two characters.
+type Kerning struct {
+	First, Second rune
+	Amount        int
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Read decodes a BMFont description, either in text or binary format.
+func Read(r io.Reader) (*Font, error) {
+	var b bytes.Buffer
+	_, err := b.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b.Bytes(), []byte("BMF")) {
+		return decodeBinary(b.Bytes())
+	}
+	return decodeText(b.Bytes())
+}
+
+// Open reads the BMFont description at the given path.
+func Open(name string) (*Font, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Char returns the description of a character, or nil if it is not in the font.
+func (f *Font) Char(id rune) *Char {
+	for i := range f.Chars {
+		if f.Chars[i].ID == id {
+			return &f.Chars[i]
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func decodeText(b []byte) (*Font, error) {
+	f := &Font{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	n := 0
+	for s.Scan() {
+		n++
+		tag, attrs, err := parseLine(s.Text())
+		if err != nil {
+			return nil, errors.New("bmfont: line " + strconv.Itoa(n) + ": " + err.Error())
+		}
+		switch tag {
+		case "info":
+			f.Face = attrs.str("face")
+			f.Size = attrs.int("size")
+			f.Bold = attrs.int("bold") != 0
+			f.Italic = attrs.int("italic") != 0
+			f.Unicode = attrs.int("unicode") != 0
+			copy(f.Padding[:], attrs.ints("padding"))
+			copy(f.Spacing[:], attrs.ints("spacing"))
+		case "common":
+			f.LineHeight = attrs.int("lineHeight")
+			f.Base = attrs.int("base")
+			f.ScaleW = attrs.int("scaleW")
+			f.ScaleH = attrs.int("scaleH")
+			pages := attrs.int("pages")
+			if pages < 0 || pages > 0x100 {
+				return nil, errors.New("bmfont: line " + strconv.Itoa(n) + ": invalid page count")
+			}
+			f.Pages = make([]string, pages)
+		case "page":
+			id := attrs.int("id")
+			if id < 0 || id > 0xFF {
+				return nil, errors.New("bmfont: line " + strconv.Itoa(n) + ": invalid page id")
+			}
+			for len(f.Pages) <= id {
+				f.Pages = append(f.Pages, "")
+			}
+			f.Pages[id] = attrs.str("file")
+		case "char":
+			f.Chars = append(f.Chars, Char{
+				ID:       rune(attrs.int("id")),
+				X:        attrs.int("x"),
+				Y:        attrs.int("y"),
+				Width:    attrs.int("width"),
+				Height:   attrs.int("height"),
+				XOffset:  attrs.int("xoffset"),
+				YOffset:  attrs.int("yoffset"),
+				XAdvance: attrs.int("xadvance"),
+				Page:     attrs.int("page"),
+				Channel:  attrs.int("chnl"),
+			})
+		case "kerning":
+			f.Kernings = append(f.Kernings, Kerning{
+				First:  rune(attrs.int("first")),
+				Second: rune(attrs.int("second")),
+				Amount: attrs.int("amount"),
+			})
+		}
+		if attrs.err != nil {
+			return nil, errors.New("bmfont: line " + strconv.Itoa(n) + ": " + attrs.err.Error())
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if f.LineHeight == 0 {
+		return nil, errors.New("bmfont: missing common block")
+	}
+	return f, nil
+}
+
+// attributes are the key=value pairs of a line of the text format. The first
+// conversion error is kept.
+type attributes struct {
+	values map[string]string
+	err    error
+}
+
+// parseLine splits a line of the text format into its tag and attributes.
+func parseLine(l string) (string, *attributes, error) {
+	a := &attributes{values: map[string]string{}}
+	l = strings.TrimSpace(l)
+	i := strings.IndexAny(l, " \t")
+	if i < 0 {
+		return l, a, nil
+	}
+	tag, l := l[:i], l[i:]
+	for {
+		l = strings.TrimLeft(l, " \t")
+		if l == "" {
+			return tag, a, nil
+		}
+		e := strings.IndexByte(l, '=')
+		if e <= 0 {
+			return "", nil, errors.New("invalid attribute")
+		}
+		k := l[:e]
+		l = l[e+1:]
+		var v string
+		if strings.HasPrefix(l, `"`) {
+			q := strings.IndexByte(l[1:], '"')
+			if q < 0 {
+				return "", nil, errors.New("unterminated string")
+			}
+			v, l = l[1:q+1], l[q+2:]
+		} else {
+			s := strings.IndexAny(l, " \t")
+			if s < 0 {
+				s = len(l)
+			}
+			v, l = l[:s], l[s:]
+		}
+		a.values[k] = v
+	}
+}
+
+func (a *attributes) str(k string) string {
+	return a.values[k]
+}
+
+func (a *attributes) int(k string) int {
+	s, ok := a.values[k]
+	if !ok {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil && a.err == nil {
+		a.err = errors.New(`invalid value for "` + k + `"`)
+	}
+	return v
+}
+
+func (a *attributes) ints(k string) []int {
+	s, ok := a.values[k]
+	if !ok {
+		return nil
+	}
+	var v []int
+	for _, f := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(f)
+		if err != nil && a.err == nil {
+			a.err = errors.New(`invalid value for "` + k + `"`)
+		}
+		v = append(v, i)
+	}
+	return v
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+var errEOF = errors.New("bmfont: unexpected end of data")
+
+func decodeBinary(b []byte) (*Font, error) {
+	if len(b) < 4 || b[3] != 3 {
+		return nil, errors.New("bmfont: unsupported version of the binary format")
+	}
+	f := &Font{}
+	common := false
+	b = b[4:]
+	for len(b) > 0 {
+		if len(b) < 5 {
+			return nil, errEOF
+		}
+		typ, n := b[0], int(u32(b[1:]))
+		b = b[5:]
+		if n < 0 || n > len(b) {
+			return nil, errEOF
+		}
+		d := b[:n]
+		b = b[n:]
+
+		switch typ {
+		case 1: // info
+			if len(d) < 14 {
+				return nil, errEOF
+			}
+			f.Size = int(int16(u16(d)))
+			f.Unicode = d[2]&0x02 != 0
+			f.Italic = d[2]&0x04 != 0
+			f.Bold = d[2]&0x08 != 0
+			for i := range f.Padding {
+				f.Padding[i] = int(d[7+i])
+			}
+			f.Spacing = [2]int{int(d[11]), int(d[12])}
+			f.Face = cstrings(d[14:])[0]
+
+		case 2: // common
+			if len(d) < 15 {
+				return nil, errEOF
+			}
+			f.LineHeight = int(u16(d))
+			f.Base = int(u16(d[2:]))
+			f.ScaleW = int(u16(d[4:]))
+			f.ScaleH = int(u16(d[6:]))
+			common = true
+
+		case 3: // pages
+			f.Pages = cstrings(d)
+
+		case 4: // chars
+			for ; len(d) >= 20; d = d[20:] {
+				f.Chars = append(f.Chars, Char{
+					ID:       rune(u32(d)),
+					X:        int(u16(d[4:])),
+					Y:        int(u16(d[6:])),
+					Width:    int(u16(d[8:])),
+					Height:   int(u16(d[10:])),
+					XOffset:  int(int16(u16(d[12:]))),
+					YOffset:  int(int16(u16(d[14:]))),
+					XAdvance: int(int16(u16(d[16:]))),
+					Page:     int(d[18]),
+					Channel:  int(d[19]),
+				})
+			}
+
+		case 5: // kerning pairs
+			for ; len(d) >= 10; d = d[10:] {
+				f.Kernings = append(f.Kernings, Kerning{
+					First:  rune(u32(d)),
+					Second: rune(u32(d[4:])),
+					Amount: int(int16(u16(d[8:]))),
+				})
+			}
+		}
+	}
+	if !common {
+		return nil, errors.New("bmfont: missing common block")
+	}
+	return f, nil
+}
+
+func u16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func u32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+// cstrings splits a sequence of null-terminated strings.
+func cstrings(b []byte) []string {
+	s := strings.Split(string(b), "\x00")
+	if len(s) > 1 && s[len(s)-1] == "" {
+		s = s[:len(s)-1]
+	}
+	return s
+}
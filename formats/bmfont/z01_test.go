@@ -0,0 +1,70 @@
+package bmfont_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cozely/cozely/formats/bmfont"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOpen(t *testing.T) {
+	txt, err := bmfont.Open("testdata/font.fnt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := bmfont.Open("testdata/font_bin.fnt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []*bmfont.Font{txt, bin} {
+		if f.Face != "Tiny Test" || f.Size != 8 || !f.Bold || f.Italic || !f.Unicode {
+			t.Errorf("wrong info: %+v", f)
+		}
+		if f.Padding != [4]int{0, 1, 2, 3} || f.Spacing != [2]int{1, 1} {
+			t.Errorf("wrong padding or spacing: %v %v", f.Padding, f.Spacing)
+		}
+		if f.LineHeight != 8 || f.Base != 6 || f.ScaleW != 16 || f.ScaleH != 8 {
+			t.Errorf("wrong common block: %+v", f)
+		}
+		if !reflect.DeepEqual(f.Pages, []string{"font_0.png"}) {
+			t.Errorf("wrong pages: %q", f.Pages)
+		}
+		if len(f.Chars) != 3 {
+			t.Fatalf("wrong number of chars: %d", len(f.Chars))
+		}
+		want := bmfont.Char{ID: 'g', X: 8, Width: 3, Height: 6, XOffset: -1, YOffset: 3, XAdvance: 3, Channel: 15}
+		if c := f.Char('g'); c == nil || *c != want {
+			t.Errorf("wrong char: %+v", c)
+		}
+		if f.Char('z') != nil {
+			t.Errorf("missing char found")
+		}
+		if !reflect.DeepEqual(f.Kernings, []bmfont.Kerning{{First: 'A', Second: 'V', Amount: -1}}) {
+			t.Errorf("wrong kernings: %+v", f.Kernings)
+		}
+	}
+}
+
+func TestPageIDs(t *testing.T) {
+	f, err := bmfont.Read(strings.NewReader("common lineHeight=8 pages=1\npage id=255 file=\"last.png\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Pages) != 256 || f.Pages[255] != "last.png" {
+		t.Errorf("page 255 not read: %d pages", len(f.Pages))
+	}
+	_, err = bmfont.Read(strings.NewReader("common lineHeight=8\npage id=256 file=\"x.png\"\n"))
+	if err == nil {
+		t.Errorf("page 256 accepted")
+	}
+	for _, n := range []string{"-1", "257", "1000000000000"} {
+		_, err = bmfont.Read(strings.NewReader("common lineHeight=8 pages=" + n + "\n"))
+		if err == nil {
+			t.Errorf("page count %s accepted", n)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package ldtk reads projects made with the LDtk level editor (".ldtk" JSON
+// files, with their optional external level files).
+package ldtk
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Project is the content of an LDtk file.
+type Project struct {
+	JSONVersion     string `json:"jsonVersion"`
+	DefaultGridSize int    `json:"defaultGridSize"`
+	BgColor         string `json:"bgColor"`
+	ExternalLevels  bool   `json:"externalLevels"`
+	Defs            struct {
+		Tilesets []*Tileset `json:"tilesets"`
+	} `json:"defs"`
+	Levels []*Level `json:"levels"`
+}
+
+// A Tileset is the definition of a tileset.
+type Tileset struct {
+	UID          int    `json:"uid"`
+	Identifier   string `json:"identifier"`
+	RelPath      string `json:"relPath"` // path of the image, relative to the project
+	PxWid        int    `json:"pxWid"`   // size of the image
+	PxHei        int    `json:"pxHei"`
+	TileGridSize int    `json:"tileGridSize"`
+	Spacing      int    `json:"spacing"`
+	Padding      int    `json:"padding"`
+	CWid         int    `json:"__cWid"` // size in tiles
+	CHei         int    `json:"__cHei"`
+}
+
+// A Level is a part of the world, made of layers.
+type Level struct {
+	Identifier      string   `json:"identifier"`
+	IID             string   `json:"iid"`
+	UID             int      `json:"uid"`
+	WorldX          int      `json:"worldX"`
+	WorldY          int      `json:"worldY"`
+	PxWid           int      `json:"pxWid"`
+	PxHei           int      `json:"pxHei"`
+	BgColor         string   `json:"__bgColor"`
+	ExternalRelPath string   `json:"externalRelPath"`
+	FieldInstances  Fields   `json:"fieldInstances"`
+	LayerInstances  []*Layer `json:"layerInstances"` // from top to bottom
+}
+
+// Types of layers.
+const (
+	IntGrid   = "IntGrid"
+	Entities  = "Entities"
+	Tiles     = "Tiles"
+	AutoLayer = "AutoLayer"
+)
+
+// A Layer is an instance of a layer in a level.
+type Layer struct {
+	Identifier      string         `json:"__identifier"`
+	Type            string         `json:"__type"` // IntGrid, Entities, Tiles or AutoLayer
+	CWid            int            `json:"__cWid"` // size in cells
+	CHei            int            `json:"__cHei"`
+	GridSize        int            `json:"__gridSize"`
+	Opacity         float64        `json:"__opacity"`
+	TilesetDefUID   *int           `json:"__tilesetDefUid"`
+	TilesetRelPath  *string        `json:"__tilesetRelPath"`
+	PxOffsetX       int            `json:"__pxTotalOffsetX"`
+	PxOffsetY       int            `json:"__pxTotalOffsetY"`
+	Visible         bool           `json:"visible"`
+	IntGridCSV      []int          `json:"intGridCsv"` // row by row, 0 for empty
+	GridTiles       []TileInstance `json:"gridTiles"`
+	AutoLayerTiles  []TileInstance `json:"autoLayerTiles"`
+	EntityInstances []*Entity      `json:"entityInstances"`
+}
+
+// A TileInstance is a tile placed in a layer.
+type TileInstance struct {
+	Px  [2]int `json:"px"`  // position in the layer, in pixels
+	Src [2]int `json:"src"` // position in the tileset image, in pixels
+	F   int    `json:"f"`   // flip bits: 1 for X, 2 for Y
+	T   int    `json:"t"`   // ID of the tile in the tileset
+}
+
+// Flip bits of a tile instance.
+const (
+	FlipX = 1
+	FlipY = 2
+)
+
+// An Entity is an instance of an entity in a layer.
+type Entity struct {
+	Identifier     string     `json:"__identifier"`
+	IID            string     `json:"iid"`
+	Grid           [2]int     `json:"__grid"` // position, in cells
+	Pivot          [2]float64 `json:"__pivot"`
+	Px             [2]int     `json:"px"` // position of the pivot, in pixels
+	Width          int        `json:"width"`
+	Height         int        `json:"height"`
+	Tags           []string   `json:"__tags"`
+	FieldInstances Fields     `json:"fieldInstances"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Field is a custom value attached to a level or an entity.
+type Field struct {
+	Identifier string          `json:"__identifier"`
+	Type       string          `json:"__type"` // e.g. "Int", "String", "Color", "Array<Point>"
+	Value      json.RawMessage `json:"__value"`
+}
+
+// Fields is a list of custom values.
+type Fields []Field
+
+// Get returns a field, or nil if there is none with this identifier.
+func (f Fields) Get(identifier string) *Field {
+	for i := range f {
+		if f[i].Identifier == identifier {
+			return &f[i]
+		}
+	}
+	return nil
+}
+
+// Decode stores the value of the field in the value pointed to by v (see
+// json.Unmarshal).
+func (f *Field) Decode(v interface{}) error {
+	if f == nil {
+		return errors.New("ldtk: field not found")
+	}
+	return json.Unmarshal(f.Value, v)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Read reads a project. External levels are not read (see Open).
+func Read(r io.Reader) (*Project, error) {
+	var p Project
+	err := json.NewDecoder(r).Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Open reads a project file, and the files of its external levels.
+func Open(name string) (*Project, error) {
+	f, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := Read(f)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Dir(filepath.ToSlash(name))
+	for i, l := range p.Levels {
+		if l.ExternalRelPath == "" {
+			continue
+		}
+		el, err := openLevel(path.Join(dir, l.ExternalRelPath))
+		if err != nil {
+			return nil, err
+		}
+		el.ExternalRelPath = l.ExternalRelPath
+		p.Levels[i] = el
+	}
+
+	return p, nil
+}
+
+func openLevel(name string) (*Level, error) {
+	f, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var l Level
+	err = json.NewDecoder(f).Decode(&l)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Level returns the level with the given identifier, or nil.
+func (p *Project) Level(identifier string) *Level {
+	for _, l := range p.Levels {
+		if l.Identifier == identifier {
+			return l
+		}
+	}
+	return nil
+}
+
+// Tileset returns the definition of a tileset, or nil.
+func (p *Project) Tileset(uid int) *Tileset {
+	for _, ts := range p.Defs.Tilesets {
+		if ts.UID == uid {
+			return ts
+		}
+	}
+	return nil
+}
+
+// Layer returns the layer with the given identifier, or nil.
+func (l *Level) Layer(identifier string) *Layer {
+	for _, y := range l.LayerInstances {
+		if y.Identifier == identifier {
+			return y
+		}
+	}
+	return nil
+}
+
+// Tiles returns the tiles of the layer: the grid tiles for tile layers, and
+// the automatic tiles for auto-layers and IntGrid layers.
+func (l *Layer) Tiles() []TileInstance {
+	if l.Type == Tiles {
+		return l.GridTiles
+	}
+	return l.AutoLayerTiles
+}
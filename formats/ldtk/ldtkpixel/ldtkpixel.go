@@ -0,0 +1,61 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package ldtkpixel creates pixel sprite sheets and tilemaps from the projects
+// read by package ldtk.
+package ldtkpixel
+
+import (
+	"errors"
+	"path"
+
+	"github.com/cozely/cozely/formats/ldtk"
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Sheet declares a pixel sprite sheet for the image of a tileset, so that
+// each tile becomes a frame (with the same ID). The image path is joined to
+// dir (usually the directory of the project, relative to the assets), and must
+// be a PNG file; its colors are converted to the palette according to
+// pixel.SetColorMapping.
+//
+// Tilesets with spacing or padding are not supported.
+func Sheet(ts *ldtk.Tileset, dir string) (pixel.SheetID, error) {
+	if ts.Spacing != 0 || ts.Padding != 0 {
+		return 0, errors.New("ldtk: spacing and padding not supported in tileset " + ts.Identifier)
+	}
+	if path.Ext(ts.RelPath) != ".png" {
+		return 0, errors.New("ldtk: image of tileset " + ts.Identifier + " is not a PNG file")
+	}
+	p := path.Join(dir, ts.RelPath)
+	p = p[:len(p)-len(".png")]
+	g := int16(ts.TileGridSize)
+	return pixel.Sheet(p, pixel.XY{g, g}), nil
+}
+
+// Tilemap creates a pixel tilemap from the tiles of a layer, displayed with the
+// frames of sheet (see Sheet). Since a tilemap holds only one tile per
+// cell, stacked tiles are replaced by the last one.
+func Tilemap(l *ldtk.Layer, sheet pixel.SheetID) (*pixel.Tilemap, error) {
+	if l.GridSize <= 0 || l.TilesetDefUID == nil {
+		return nil, errors.New("ldtk: no tiles in layer " + l.Identifier)
+	}
+
+	tm := pixel.NewTilemap(sheet, pixel.XY{int16(l.CWid), int16(l.CHei)})
+	for _, t := range l.Tiles() {
+		o := pixel.Upright
+		if t.F&ldtk.FlipX != 0 {
+			o |= pixel.FlipX
+		}
+		if t.F&ldtk.FlipY != 0 {
+			o |= pixel.FlipY
+		}
+		tm.Set(
+			pixel.XY{int16(t.Px[0] / l.GridSize), int16(t.Px[1] / l.GridSize)},
+			pixel.Tile{Frame: int16(t.T), Orientation: o},
+		)
+	}
+	return tm, nil
+}
@@ -0,0 +1,48 @@
+package ldtkpixel_test
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/formats/ldtk"
+	"github.com/cozely/cozely/formats/ldtk/ldtkpixel"
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTilemap(t *testing.T) {
+	p, err := ldtk.Open("../testdata/world.ldtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := ldtkpixel.Sheet(p.Tileset(1), "maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := ldtkpixel.Tilemap(p.Level("Level_0").Layer("Ground"), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm.Size() != (pixel.XY{4, 2}) {
+		t.Errorf("wrong tilemap size: %v", tm.Size())
+	}
+	cases := []struct {
+		cell pixel.XY
+		tile pixel.Tile
+	}{
+		{pixel.XY{0, 0}, pixel.Tile{Frame: 0}},
+		{pixel.XY{1, 0}, pixel.Tile{Frame: 1, Orientation: pixel.FlipX}},
+		{pixel.XY{2, 0}, pixel.NoTile},
+		{pixel.XY{3, 1}, pixel.Tile{Frame: 6, Orientation: pixel.Rotate180}},
+	}
+	for _, c := range cases {
+		if tl := tm.Tile(c.cell); tl != c.tile {
+			t.Errorf("tile at %v: got %+v, want %+v", c.cell, tl, c.tile)
+		}
+	}
+
+	if _, err := ldtkpixel.Tilemap(p.Level("Level_0").Layer("Entities"), s); err == nil {
+		t.Errorf("tilemap created from an entity layer")
+	}
+}
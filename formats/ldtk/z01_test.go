@@ -0,0 +1,61 @@
+package ldtk_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cozely/cozely/formats/ldtk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOpen(t *testing.T) {
+	p, err := ldtk.Open("testdata/world.ldtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.JSONVersion != "1.5.3" || p.DefaultGridSize != 8 || len(p.Levels) != 2 {
+		t.Fatalf("wrong project: %+v", p)
+	}
+	ts := p.Tileset(1)
+	if ts == nil || ts.RelPath != "tiles.png" || ts.CWid != 4 {
+		t.Errorf("wrong tileset: %+v", ts)
+	}
+
+	for _, n := range []string{"Level_0", "Level_1"} {
+		l := p.Level(n)
+		if l == nil || len(l.LayerInstances) != 3 {
+			t.Fatalf("wrong level %s: %+v", n, l)
+		}
+		var s string
+		if err := l.FieldInstances.Get("music").Decode(&s); err != nil || s != "theme" {
+			t.Errorf("wrong level field: %q (%v)", s, err)
+		}
+
+		e := l.Layer("Entities").EntityInstances
+		if len(e) != 1 || e[0].Identifier != "Player" || e[0].Grid != [2]int{1, 1} || e[0].Px != [2]int{12, 16} {
+			t.Fatalf("wrong entities: %+v", e)
+		}
+		var lives int
+		if err := e[0].FieldInstances.Get("lives").Decode(&lives); err != nil || lives != 3 {
+			t.Errorf("wrong int field: %d (%v)", lives, err)
+		}
+		var path []struct{ CX, CY int }
+		if err := e[0].FieldInstances.Get("path").Decode(&path); err != nil || len(path) != 2 || path[1].CX != 3 {
+			t.Errorf("wrong point array field: %+v (%v)", path, err)
+		}
+		if err := e[0].FieldInstances.Get("none").Decode(&s); err == nil {
+			t.Errorf("missing field decoded without error")
+		}
+
+		w := l.Layer("Walls")
+		if !reflect.DeepEqual(w.IntGridCSV, []int{1, 1, 0, 0, 0, 0, 1, 1}) || w.Visible || len(w.Tiles()) != 1 {
+			t.Errorf("wrong IntGrid layer: %+v", w)
+		}
+	}
+
+	if p.Level("Level_1").ExternalRelPath != "world/Level_1.ldtkl" {
+		t.Errorf("wrong external level path: %q", p.Level("Level_1").ExternalRelPath)
+	}
+}
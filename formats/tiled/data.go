@@ -0,0 +1,76 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// decodeData decodes the tiles of a layer, stored as text.
+func decodeData(s string, encoding, compression string) ([]GID, error) {
+	switch encoding {
+	case "csv":
+		var d []GID
+		for _, v := range strings.Split(s, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			g, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return nil, errors.New("tiled: invalid tile in CSV data")
+			}
+			d = append(d, GID(g))
+		}
+		return d, nil
+
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+
+		var r io.Reader
+		switch compression {
+		case "":
+		case "zlib":
+			r, err = zlib.NewReader(bytes.NewReader(b))
+		case "gzip":
+			r, err = gzip.NewReader(bytes.NewReader(b))
+		default:
+			return nil, errors.New(`tiled: compression "` + compression + `" not supported`)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			b, err = ioutil.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(b)%4 != 0 {
+			return nil, errors.New("tiled: invalid length of base64 data")
+		}
+		d := make([]GID, len(b)/4)
+		for i := range d {
+			d[i] = GID(binary.LittleEndian.Uint32(b[4*i:]))
+		}
+		return d, nil
+	}
+
+	return nil, errors.New(`tiled: encoding "` + encoding + `" not supported`)
+}
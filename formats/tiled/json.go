@@ -0,0 +1,337 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package tiled
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type jsonMap struct {
+	Orientation     string
+	Width           int
+	Height          int
+	TileWidth       int
+	TileHeight      int
+	Infinite        bool
+	BackgroundColor string
+	Class           string
+	Properties      []jsonProp
+	Tilesets        []jsonTileset
+	Layers          []jsonLayer
+}
+
+type jsonProp struct {
+	Name  string
+	Type  string
+	Value json.RawMessage
+}
+
+type jsonTileset struct {
+	FirstGID    GID
+	Source      string
+	Name        string
+	Class       string
+	TileWidth   int
+	TileHeight  int
+	Spacing     int
+	Margin      int
+	TileCount   int
+	Columns     int
+	Image       string
+	ImageWidth  int
+	ImageHeight int
+	Properties  []jsonProp
+	Tiles       []struct {
+		ID         int
+		Type       string
+		Class      string
+		Properties []jsonProp
+		Animation  []struct {
+			TileID   int
+			Duration int
+		}
+	}
+}
+
+type jsonLayer struct {
+	ID          int
+	Name        string
+	Type        string
+	Class       string
+	Visible     *bool
+	Opacity     *float64
+	OffsetX     float64
+	OffsetY     float64
+	ParallaxX   *float64
+	ParallaxY   *float64
+	Properties  []jsonProp
+	Width       int
+	Height      int
+	Encoding    string
+	Compression string
+	Data        json.RawMessage
+	Objects     []jsonObject
+	Image       string
+	Layers      []jsonLayer
+}
+
+type jsonObject struct {
+	ID       int
+	Name     string
+	Type     string
+	Class    string
+	X        float64
+	Y        float64
+	Width    float64
+	Height   float64
+	Rotation float64
+	GID      GID
+	Visible  *bool
+	Point    bool
+	Ellipse  bool
+	Polygon  []Point
+	Polyline []Point
+	Text     *struct {
+		Text string
+	}
+	Properties []jsonProp
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ReadJSON reads a map in the JSON format. External tilesets are not read
+// (only their Source and FirstGID are set).
+func ReadJSON(r io.Reader) (*Map, error) {
+	var j jsonMap
+	err := json.NewDecoder(r).Decode(&j)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Map{
+		Orientation:     j.Orientation,
+		Width:           j.Width,
+		Height:          j.Height,
+		TileWidth:       j.TileWidth,
+		TileHeight:      j.TileHeight,
+		Infinite:        j.Infinite,
+		BackgroundColor: j.BackgroundColor,
+		Class:           j.Class,
+	}
+	m.Properties, err = jsonProperties(j.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range j.Tilesets {
+		ts, err := j.Tilesets[i].tileset()
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, ts)
+	}
+
+	m.Layers, err = jsonLayers(j.Layers, m.Infinite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ReadTilesetJSON reads an external tileset in the JSON format.
+func ReadTilesetJSON(r io.Reader) (*Tileset, error) {
+	var j jsonTileset
+	err := json.NewDecoder(r).Decode(&j)
+	if err != nil {
+		return nil, err
+	}
+	return j.tileset()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (j *jsonTileset) tileset() (*Tileset, error) {
+	ts := Tileset{
+		FirstGID:    j.FirstGID,
+		Source:      j.Source,
+		Name:        j.Name,
+		Class:       j.Class,
+		TileWidth:   j.TileWidth,
+		TileHeight:  j.TileHeight,
+		Spacing:     j.Spacing,
+		Margin:      j.Margin,
+		TileCount:   j.TileCount,
+		Columns:     j.Columns,
+		Image:       j.Image,
+		ImageWidth:  j.ImageWidth,
+		ImageHeight: j.ImageHeight,
+	}
+	var err error
+	ts.Properties, err = jsonProperties(j.Properties)
+	if err != nil {
+		return nil, err
+	}
+	for _, jt := range j.Tiles {
+		t := Tile{
+			ID:    jt.ID,
+			Class: either(jt.Class, jt.Type),
+		}
+		t.Properties, err = jsonProperties(jt.Properties)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range jt.Animation {
+			t.Animation = append(t.Animation, Frame{TileID: f.TileID, Duration: f.Duration})
+		}
+		ts.Tiles = append(ts.Tiles, t)
+	}
+	return &ts, nil
+}
+
+func jsonLayers(jj []jsonLayer, infinite bool) ([]*Layer, error) {
+	var ll []*Layer
+	for i := range jj {
+		j := &jj[i]
+		l := Layer{
+			ID:        j.ID,
+			Name:      j.Name,
+			Type:      j.Type,
+			Class:     j.Class,
+			Visible:   j.Visible == nil || *j.Visible,
+			Opacity:   orDefault(j.Opacity, 1),
+			OffsetX:   j.OffsetX,
+			OffsetY:   j.OffsetY,
+			ParallaxX: orDefault(j.ParallaxX, 1),
+			ParallaxY: orDefault(j.ParallaxY, 1),
+		}
+		var err error
+		l.Properties, err = jsonProperties(j.Properties)
+		if err != nil {
+			return nil, err
+		}
+
+		switch j.Type {
+		case TileLayer:
+			l.Width, l.Height = j.Width, j.Height
+			if infinite {
+				break
+			}
+			if j.Encoding == "base64" {
+				var s string
+				err = json.Unmarshal(j.Data, &s)
+				if err == nil {
+					l.Data, err = decodeData(s, j.Encoding, j.Compression)
+				}
+			} else {
+				err = json.Unmarshal(j.Data, &l.Data)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(l.Data) != l.Width*l.Height {
+				return nil, errors.New("tiled: wrong number of tiles in layer " + l.Name)
+			}
+
+		case ObjectGroup:
+			for k := range j.Objects {
+				o, err := j.Objects[k].object()
+				if err != nil {
+					return nil, err
+				}
+				l.Objects = append(l.Objects, o)
+			}
+
+		case ImageLayer:
+			l.Image = j.Image
+
+		case Group:
+			l.Layers, err = jsonLayers(j.Layers, infinite)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, errors.New(`tiled: unknown layer type "` + j.Type + `"`)
+		}
+
+		ll = append(ll, &l)
+	}
+	return ll, nil
+}
+
+func (j *jsonObject) object() (*Object, error) {
+	o := Object{
+		ID:       j.ID,
+		Name:     j.Name,
+		Class:    either(j.Class, j.Type),
+		X:        j.X,
+		Y:        j.Y,
+		Width:    j.Width,
+		Height:   j.Height,
+		Rotation: j.Rotation,
+		GID:      j.GID,
+		Visible:  j.Visible == nil || *j.Visible,
+		Point:    j.Point,
+		Ellipse:  j.Ellipse,
+		Polygon:  j.Polygon,
+		Polyline: j.Polyline,
+	}
+	if j.Text != nil {
+		o.Text = j.Text.Text
+	}
+	var err error
+	o.Properties, err = jsonProperties(j.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// jsonProperties converts the properties to strings, using the same
+// representation as the XML format.
+func jsonProperties(jj []jsonProp) (Properties, error) {
+	var pp Properties
+	for _, j := range jj {
+		p := Property{Name: j.Name, Type: j.Type}
+		if p.Type == "" {
+			p.Type = "string"
+		}
+
+		var v interface{}
+		if len(j.Value) > 0 {
+			err := json.Unmarshal(j.Value, &v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch v := v.(type) {
+		case nil:
+		case string:
+			p.Value = v
+		case bool:
+			p.Value = strconv.FormatBool(v)
+		case float64:
+			p.Value = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			// Class properties
+			p.Value = string(j.Value)
+		}
+
+		pp = append(pp, p)
+	}
+	return pp, nil
+}
+
+func orDefault(v *float64, def float64) float64 {
+	if v == nil {
+		return def
+	}
+	return *v
+}
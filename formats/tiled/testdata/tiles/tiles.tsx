@@ -0,0 +1,14 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" tiledversion="1.10.2" name="tiles" tilewidth="8" tileheight="8" tilecount="16" columns="4">
+ <image source="tiles.png" width="32" height="32"/>
+ <tile id="5" type="water">
+  <properties>
+   <property name="solid" type="bool" value="false"/>
+  </properties>
+  <animation>
+   <frame tileid="5" duration="100"/>
+   <frame tileid="6" duration="100"/>
+   <frame tileid="7" duration="100"/>
+  </animation>
+ </tile>
+</tileset>
@@ -0,0 +1,259 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package tiled reads maps made with the Tiled editor, in both the XML (".tmx")
+// and JSON formats.
+//
+// Only orthogonal, finite maps are fully supported: the layers of infinite maps
+// are read without their tiles.
+package tiled
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Map is the content of a Tiled map file.
+type Map struct {
+	Orientation     string // "orthogonal", "isometric", "staggered" or "hexagonal"
+	Width, Height   int    // in tiles
+	TileWidth       int    // in pixels
+	TileHeight      int    // in pixels
+	Infinite        bool
+	BackgroundColor string
+	Class           string
+	Properties      Properties
+	Tilesets        []*Tileset
+	Layers          []*Layer
+}
+
+// A Tileset is a collection of tiles, either embedded in the map or in an
+// external file.
+type Tileset struct {
+	FirstGID    GID    // GID of the first tile of the set in the map
+	Source      string // path of the external file, if any
+	Name        string
+	Class       string
+	TileWidth   int
+	TileHeight  int
+	Spacing     int // space between tiles in the image
+	Margin      int // space around the tiles in the image
+	TileCount   int
+	Columns     int
+	Image       string // path of the image, relative to the file of the tileset
+	ImageWidth  int
+	ImageHeight int
+	Properties  Properties
+	Tiles       []Tile // tiles with custom data
+}
+
+// A Tile holds the custom data of a tile in a tileset.
+type Tile struct {
+	ID         int // index in the tileset
+	Class      string
+	Properties Properties
+	Animation  []Frame
+}
+
+// A Frame is a step of a tile animation.
+type Frame struct {
+	TileID   int // index in the tileset
+	Duration int // in milliseconds
+}
+
+// Types of layers.
+const (
+	TileLayer   = "tilelayer"
+	ObjectGroup = "objectgroup"
+	ImageLayer  = "imagelayer"
+	Group       = "group"
+)
+
+// A Layer is either a grid of tiles, a group of objects, an image, or a group
+// of layers (depending on its type).
+type Layer struct {
+	ID         int
+	Name       string
+	Type       string // TileLayer, ObjectGroup, ImageLayer or Group
+	Class      string
+	Visible    bool
+	Opacity    float64
+	OffsetX    float64 // in pixels
+	OffsetY    float64
+	ParallaxX  float64 // scrolling factor
+	ParallaxY  float64
+	Properties Properties
+
+	// Tile layers
+	Width, Height int   // in tiles
+	Data          []GID // row by row
+
+	// Object groups
+	Objects []*Object
+
+	// Image layers
+	Image string
+
+	// Groups
+	Layers []*Layer
+}
+
+// An Object is a shape, a point, or a tile placed freely on the map.
+type Object struct {
+	ID         int
+	Name       string
+	Class      string
+	X, Y       float64 // in pixels; for tile objects, bottom-left corner
+	Width      float64
+	Height     float64
+	Rotation   float64 // in degrees, clockwise
+	GID        GID     // for tile objects
+	Visible    bool
+	Point      bool
+	Ellipse    bool
+	Polygon    []Point // relative to the position of the object
+	Polyline   []Point
+	Text       string
+	Properties Properties
+}
+
+// A Point is used for polygons and polylines.
+type Point struct {
+	X, Y float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Property is a custom value attached to a map, layer, tileset, tile or
+// object. Values are converted to strings; for colors, Tiled uses the format
+// "#AARRGGBB".
+type Property struct {
+	Name  string
+	Type  string // "string", "int", "float", "bool", "color", "file", "object" or "class"
+	Value string
+}
+
+// Properties is a list of custom values.
+type Properties []Property
+
+// Get returns the value of a property.
+func (p Properties) Get(name string) (value string, ok bool) {
+	for _, v := range p {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A GID is a global tile ID: it identifies both a tileset and a tile in this
+// set. The highest bits are used as flags. The value 0 means no tile.
+type GID uint32
+
+// Flags of a GID. The diagonal flip (i.e. the swap of the X and Y axes) is done
+// first, followed by the horizontal and vertical flips.
+const (
+	FlippedHorizontally GID = 0x80000000
+	FlippedVertically   GID = 0x40000000
+	FlippedDiagonally   GID = 0x20000000
+	RotatedHexagonal120 GID = 0x10000000
+
+	flags = FlippedHorizontally | FlippedVertically | FlippedDiagonally | RotatedHexagonal120
+)
+
+// ID returns the GID without its flags.
+func (g GID) ID() GID {
+	return g &^ flags
+}
+
+// Flags returns the flags of the GID.
+func (g GID) Flags() GID {
+	return g & flags
+}
+
+// Tileset returns the tileset of a GID, and the index of the tile in this set.
+// It returns nil for an empty cell (or an invalid GID).
+func (m *Map) Tileset(g GID) (*Tileset, int) {
+	id := g.ID()
+	if id == 0 {
+		return nil, 0
+	}
+	var ts *Tileset
+	for _, t := range m.Tilesets {
+		if t.FirstGID <= id && (ts == nil || t.FirstGID > ts.FirstGID) {
+			ts = t
+		}
+	}
+	if ts == nil {
+		return nil, 0
+	}
+	return ts, int(id - ts.FirstGID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Open reads a map file, in either format (depending on the extension: ".tmx"
+// for XML, ".json" or ".tmj" for JSON). External tilesets are also read, and
+// their image paths made relative to the directory of the map.
+func Open(name string) (*Map, error) {
+	f, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m *Map
+	switch strings.ToLower(path.Ext(name)) {
+	case ".tmx":
+		m, err = ReadTMX(f)
+	case ".json", ".tmj":
+		m, err = ReadJSON(f)
+	default:
+		return nil, errors.New("tiled: unknown map extension for " + name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Dir(filepath.ToSlash(name))
+	for i, ts := range m.Tilesets {
+		if ts.Source == "" {
+			continue
+		}
+		ets, err := openTileset(path.Join(dir, ts.Source))
+		if err != nil {
+			return nil, err
+		}
+		ets.FirstGID = ts.FirstGID
+		ets.Source = ts.Source
+		if ets.Image != "" {
+			ets.Image = path.Join(path.Dir(ts.Source), ets.Image)
+		}
+		m.Tilesets[i] = ets
+	}
+
+	return m, nil
+}
+
+func openTileset(name string) (*Tileset, error) {
+	f, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".tsx":
+		return ReadTSX(f)
+	case ".json", ".tsj":
+		return ReadTilesetJSON(f)
+	}
+	return nil, errors.New("tiled: unknown tileset extension for " + name)
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+// Package tiledpixel creates pixel sprite sheets and tilemaps from the maps
+// read by package tiled.
+package tiledpixel
+
+import (
+	"errors"
+	"path"
+
+	"github.com/cozely/cozely/formats/tiled"
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Sheet declares a pixel sprite sheet for the image of a tileset, so that
+// each tile becomes a frame (with the same index). The image path is joined to
+// dir (usually the directory of the map, relative to the assets), and must be
+// a PNG file; its colors are converted to the palette according to
+// pixel.SetColorMapping.
+//
+// Tilesets with spacing or margin, or made of a collection of images, are not
+// supported.
+func Sheet(ts *tiled.Tileset, dir string) (pixel.SheetID, error) {
+	if ts.Image == "" {
+		return 0, errors.New("tiled: tileset " + ts.Name + " is not based on a single image")
+	}
+	if ts.Spacing != 0 || ts.Margin != 0 {
+		return 0, errors.New("tiled: spacing and margin not supported in tileset " + ts.Name)
+	}
+	if path.Ext(ts.Image) != ".png" {
+		return 0, errors.New("tiled: image of tileset " + ts.Name + " is not a PNG file")
+	}
+	p := path.Join(dir, ts.Image)
+	p = p[:len(p)-len(".png")]
+	return pixel.Sheet(p, pixel.XY{int16(ts.TileWidth), int16(ts.TileHeight)}), nil
+}
+
+// Tilemap creates a pixel tilemap from a tile layer of a map. Only the tiles
+// of the given tileset are used, displayed with the frames of sheet (see
+// Sheet); all other cells are left empty. The animations of the
+// tileset are added to the tilemap when their frames are consecutive and of
+// equal duration.
+func Tilemap(m *tiled.Map, l *tiled.Layer, ts *tiled.Tileset, sheet pixel.SheetID) (*pixel.Tilemap, error) {
+	if l.Type != tiled.TileLayer {
+		return nil, errors.New("tiled: " + l.Name + " is not a tile layer")
+	}
+	if len(l.Data) != l.Width*l.Height {
+		return nil, errors.New("tiled: no tiles in layer " + l.Name)
+	}
+
+	tm := pixel.NewTilemap(sheet, pixel.XY{int16(l.Width), int16(l.Height)})
+	for i, g := range l.Data {
+		s, id := m.Tileset(g)
+		if s != ts {
+			continue
+		}
+		tm.Set(
+			pixel.XY{int16(i % l.Width), int16(i / l.Width)},
+			pixel.Tile{Frame: int16(id), Orientation: Orientation(g)},
+		)
+	}
+
+	for _, t := range ts.Tiles {
+		a := t.Animation
+		if len(a) == 0 {
+			continue
+		}
+		ok := true
+		for i := range a {
+			if a[i].TileID != a[0].TileID+i || a[i].Duration != a[0].Duration {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		tm.Animate(int16(t.ID), sheet.Animation(
+			a[0].TileID, a[len(a)-1].TileID, float64(a[0].Duration)/1000, pixel.Loop,
+		))
+	}
+
+	return tm, nil
+}
+
+// Orientation returns the pixel orientation corresponding to the flags of a
+// GID.
+func Orientation(g tiled.GID) pixel.Orientation {
+	o := pixel.Upright
+	if g&tiled.FlippedDiagonally != 0 {
+		o |= pixel.FlipDiagonal
+	}
+	if g&tiled.FlippedHorizontally != 0 {
+		o |= pixel.FlipX
+	}
+	if g&tiled.FlippedVertically != 0 {
+		o |= pixel.FlipY
+	}
+	return o
+}
@@ -0,0 +1,45 @@
+package tiledpixel_test
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/formats/tiled"
+	"github.com/cozely/cozely/formats/tiled/tiledpixel"
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTilemap(t *testing.T) {
+	m, err := tiled.Open("../testdata/map.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := tiledpixel.Sheet(m.Tilesets[0], "maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := tiledpixel.Tilemap(m, m.Layers[0], m.Tilesets[0], s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm.Size() != (pixel.XY{4, 3}) {
+		t.Errorf("wrong tilemap size: %v", tm.Size())
+	}
+	cases := []struct {
+		cell pixel.XY
+		tile pixel.Tile
+	}{
+		{pixel.XY{0, 0}, pixel.Tile{Frame: 0}},
+		{pixel.XY{2, 0}, pixel.NoTile},
+		{pixel.XY{0, 1}, pixel.Tile{Frame: 3, Orientation: pixel.FlipX}},
+		{pixel.XY{2, 2}, pixel.Tile{Frame: 0, Orientation: pixel.Rotate90}},
+		{pixel.XY{3, 2}, pixel.Tile{Frame: 6}},
+	}
+	for _, c := range cases {
+		if tl := tm.Tile(c.cell); tl != c.tile {
+			t.Errorf("tile at %v: got %+v, want %+v", c.cell, tl, c.tile)
+		}
+	}
+}
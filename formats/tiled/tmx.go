@@ -0,0 +1,357 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package tiled
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type xmlMap struct {
+	Orientation     string       `xml:"orientation,attr"`
+	Width           int          `xml:"width,attr"`
+	Height          int          `xml:"height,attr"`
+	TileWidth       int          `xml:"tilewidth,attr"`
+	TileHeight      int          `xml:"tileheight,attr"`
+	Infinite        int          `xml:"infinite,attr"`
+	BackgroundColor string       `xml:"backgroundcolor,attr"`
+	Class           string       `xml:"class,attr"`
+	Properties      []xmlProp    `xml:"properties>property"`
+	Tilesets        []xmlTileset `xml:"tileset"`
+	Layers          []xmlLayer   `xml:",any"`
+}
+
+type xmlProp struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"`
+}
+
+type xmlTileset struct {
+	FirstGID   GID    `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	Class      string `xml:"class,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Margin     int    `xml:"margin,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	Image      struct {
+		Source string `xml:"source,attr"`
+		Width  int    `xml:"width,attr"`
+		Height int    `xml:"height,attr"`
+	} `xml:"image"`
+	Properties []xmlProp `xml:"properties>property"`
+	Tiles      []struct {
+		ID         int       `xml:"id,attr"`
+		Type       string    `xml:"type,attr"`
+		Class      string    `xml:"class,attr"`
+		Properties []xmlProp `xml:"properties>property"`
+		Animation  []struct {
+			TileID   int `xml:"tileid,attr"`
+			Duration int `xml:"duration,attr"`
+		} `xml:"animation>frame"`
+	} `xml:"tile"`
+}
+
+// xmlLayer is used for all types of layers, which are distinguished by their
+// element name.
+type xmlLayer struct {
+	XMLName    xml.Name
+	ID         int       `xml:"id,attr"`
+	Name       string    `xml:"name,attr"`
+	Class      string    `xml:"class,attr"`
+	Visible    string    `xml:"visible,attr"`
+	Opacity    string    `xml:"opacity,attr"`
+	OffsetX    float64   `xml:"offsetx,attr"`
+	OffsetY    float64   `xml:"offsety,attr"`
+	ParallaxX  string    `xml:"parallaxx,attr"`
+	ParallaxY  string    `xml:"parallaxy,attr"`
+	Properties []xmlProp `xml:"properties>property"`
+	Width      int       `xml:"width,attr"`
+	Height     int       `xml:"height,attr"`
+	Data       struct {
+		Encoding    string `xml:"encoding,attr"`
+		Compression string `xml:"compression,attr"`
+		Text        string `xml:",chardata"`
+		Tiles       []struct {
+			GID GID `xml:"gid,attr"`
+		} `xml:"tile"`
+	} `xml:"data"`
+	Objects []xmlObject `xml:"object"`
+	Image   struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+	Layers []xmlLayer `xml:",any"`
+}
+
+type xmlObject struct {
+	ID         int       `xml:"id,attr"`
+	Name       string    `xml:"name,attr"`
+	Type       string    `xml:"type,attr"`
+	Class      string    `xml:"class,attr"`
+	X          float64   `xml:"x,attr"`
+	Y          float64   `xml:"y,attr"`
+	Width      float64   `xml:"width,attr"`
+	Height     float64   `xml:"height,attr"`
+	Rotation   float64   `xml:"rotation,attr"`
+	GID        GID       `xml:"gid,attr"`
+	Visible    string    `xml:"visible,attr"`
+	Point      *struct{} `xml:"point"`
+	Ellipse    *struct{} `xml:"ellipse"`
+	Polygon    *xmlPts   `xml:"polygon"`
+	Polyline   *xmlPts   `xml:"polyline"`
+	Text       *string   `xml:"text"`
+	Properties []xmlProp `xml:"properties>property"`
+}
+
+type xmlPts struct {
+	Points string `xml:"points,attr"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ReadTMX reads a map in the XML format. External tilesets are not read (only
+// their Source and FirstGID are set).
+func ReadTMX(r io.Reader) (*Map, error) {
+	var x xmlMap
+	err := xml.NewDecoder(r).Decode(&x)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Map{
+		Orientation:     x.Orientation,
+		Width:           x.Width,
+		Height:          x.Height,
+		TileWidth:       x.TileWidth,
+		TileHeight:      x.TileHeight,
+		Infinite:        x.Infinite != 0,
+		BackgroundColor: x.BackgroundColor,
+		Class:           x.Class,
+		Properties:      xmlProperties(x.Properties),
+	}
+
+	for i := range x.Tilesets {
+		m.Tilesets = append(m.Tilesets, x.Tilesets[i].tileset())
+	}
+
+	m.Layers, err = xmlLayers(x.Layers, m.Infinite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ReadTSX reads an external tileset in the XML format.
+func ReadTSX(r io.Reader) (*Tileset, error) {
+	var x xmlTileset
+	err := xml.NewDecoder(r).Decode(&x)
+	if err != nil {
+		return nil, err
+	}
+	return x.tileset(), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (x *xmlTileset) tileset() *Tileset {
+	ts := Tileset{
+		FirstGID:    x.FirstGID,
+		Source:      x.Source,
+		Name:        x.Name,
+		Class:       x.Class,
+		TileWidth:   x.TileWidth,
+		TileHeight:  x.TileHeight,
+		Spacing:     x.Spacing,
+		Margin:      x.Margin,
+		TileCount:   x.TileCount,
+		Columns:     x.Columns,
+		Image:       x.Image.Source,
+		ImageWidth:  x.Image.Width,
+		ImageHeight: x.Image.Height,
+		Properties:  xmlProperties(x.Properties),
+	}
+	for _, xt := range x.Tiles {
+		t := Tile{
+			ID:         xt.ID,
+			Class:      either(xt.Class, xt.Type),
+			Properties: xmlProperties(xt.Properties),
+		}
+		for _, f := range xt.Animation {
+			t.Animation = append(t.Animation, Frame{TileID: f.TileID, Duration: f.Duration})
+		}
+		ts.Tiles = append(ts.Tiles, t)
+	}
+	return &ts
+}
+
+func xmlLayers(xx []xmlLayer, infinite bool) ([]*Layer, error) {
+	var ll []*Layer
+	for i := range xx {
+		x := &xx[i]
+		l := Layer{
+			ID:         x.ID,
+			Name:       x.Name,
+			Class:      x.Class,
+			Visible:    x.Visible != "0",
+			Opacity:    parseFloat(x.Opacity, 1),
+			OffsetX:    x.OffsetX,
+			OffsetY:    x.OffsetY,
+			ParallaxX:  parseFloat(x.ParallaxX, 1),
+			ParallaxY:  parseFloat(x.ParallaxY, 1),
+			Properties: xmlProperties(x.Properties),
+		}
+
+		switch x.XMLName.Local {
+		case "layer":
+			l.Type = TileLayer
+			l.Width, l.Height = x.Width, x.Height
+			if infinite {
+				break
+			}
+			if x.Data.Encoding == "" {
+				for _, t := range x.Data.Tiles {
+					l.Data = append(l.Data, t.GID)
+				}
+			} else {
+				d, err := decodeData(x.Data.Text, x.Data.Encoding, x.Data.Compression)
+				if err != nil {
+					return nil, err
+				}
+				l.Data = d
+			}
+			if len(l.Data) != l.Width*l.Height {
+				return nil, errors.New("tiled: wrong number of tiles in layer " + l.Name)
+			}
+
+		case "objectgroup":
+			l.Type = ObjectGroup
+			for j := range x.Objects {
+				o, err := x.Objects[j].object()
+				if err != nil {
+					return nil, err
+				}
+				l.Objects = append(l.Objects, o)
+			}
+
+		case "imagelayer":
+			l.Type = ImageLayer
+			l.Image = x.Image.Source
+
+		case "group":
+			l.Type = Group
+			var err error
+			l.Layers, err = xmlLayers(x.Layers, infinite)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			// Not a layer (e.g. editor settings)
+			continue
+		}
+
+		ll = append(ll, &l)
+	}
+	return ll, nil
+}
+
+func (x *xmlObject) object() (*Object, error) {
+	o := Object{
+		ID:         x.ID,
+		Name:       x.Name,
+		Class:      either(x.Class, x.Type),
+		X:          x.X,
+		Y:          x.Y,
+		Width:      x.Width,
+		Height:     x.Height,
+		Rotation:   x.Rotation,
+		GID:        x.GID,
+		Visible:    x.Visible != "0",
+		Point:      x.Point != nil,
+		Ellipse:    x.Ellipse != nil,
+		Properties: xmlProperties(x.Properties),
+	}
+	var err error
+	if x.Polygon != nil {
+		o.Polygon, err = parsePoints(x.Polygon.Points)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if x.Polyline != nil {
+		o.Polyline, err = parsePoints(x.Polyline.Points)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if x.Text != nil {
+		o.Text = *x.Text
+	}
+	return &o, nil
+}
+
+func xmlProperties(xx []xmlProp) Properties {
+	var pp Properties
+	for _, x := range xx {
+		p := Property{Name: x.Name, Type: x.Type, Value: x.Value}
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		if p.Value == "" && x.Text != "" {
+			// Multi-line strings
+			p.Value = x.Text
+		}
+		pp = append(pp, p)
+	}
+	return pp
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// parsePoints parses the list of points of a polygon or polyline ("x,y x,y").
+func parsePoints(s string) ([]Point, error) {
+	var pp []Point
+	for _, xy := range strings.Fields(s) {
+		c := strings.Split(xy, ",")
+		if len(c) != 2 {
+			return nil, errors.New(`tiled: invalid point "` + xy + `"`)
+		}
+		x, err := strconv.ParseFloat(c[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.ParseFloat(c[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		pp = append(pp, Point{x, y})
+	}
+	return pp, nil
+}
+
+func parseFloat(s string, def float64) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func either(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+package tiled_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cozely/cozely/formats/tiled"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOpen(t *testing.T) {
+	x, err := tiled.Open("testdata/map.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := tiled.Open("testdata/map.tmj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*tiled.Map{x, j} {
+		if m.Width != 4 || m.Height != 3 || m.TileWidth != 8 || m.BackgroundColor != "#102030" {
+			t.Errorf("wrong map attributes: %+v", m)
+		}
+		if v, _ := m.Properties.Get("gravity"); v != "9.5" {
+			t.Errorf("wrong float property: %q", v)
+		}
+		if v, _ := m.Properties.Get("notes"); v != "first line\nsecond line" {
+			t.Errorf("wrong multi-line property: %q", v)
+		}
+
+		ts := m.Tilesets[0]
+		if ts.Name != "tiles" || ts.FirstGID != 1 || ts.Image != "tiles/tiles.png" || ts.Columns != 4 {
+			t.Errorf("wrong external tileset: %+v", ts)
+		}
+		if len(ts.Tiles) != 1 || ts.Tiles[0].Class != "water" || len(ts.Tiles[0].Animation) != 3 {
+			t.Errorf("wrong tile data: %+v", ts.Tiles)
+		}
+		if m.Tilesets[1].Image != "inline.png" {
+			t.Errorf("wrong embedded tileset: %+v", m.Tilesets[1])
+		}
+
+		if len(m.Layers) != 3 {
+			t.Fatalf("wrong number of layers: %d", len(m.Layers))
+		}
+		g := m.Layers[1]
+		if g.Type != tiled.Group || g.ParallaxX != 0.5 || g.ParallaxY != 1 || len(g.Layers) != 1 {
+			t.Errorf("wrong group: %+v", g)
+		}
+		z := g.Layers[0]
+		if z.Visible || z.Opacity != 0.5 {
+			t.Errorf("wrong layer attributes: %+v", z)
+		}
+		if !reflect.DeepEqual(m.Layers[0].Data, z.Data) {
+			t.Errorf("CSV and base64 data differ: %v, %v", m.Layers[0].Data, z.Data)
+		}
+		if g := m.Layers[0].Data[10]; g.ID() != 1 || g.Flags() != tiled.FlippedDiagonally|tiled.FlippedHorizontally {
+			t.Errorf("wrong GID flags: %x", g)
+		}
+
+		o := m.Layers[2].Objects
+		if m.Layers[2].Class != "entities" || len(o) != 3 {
+			t.Fatalf("wrong object group: %+v", m.Layers[2])
+		}
+		if v, _ := o[0].Properties.Get("lives"); !o[0].Point || o[0].Class != "spawn" || v != "3" {
+			t.Errorf("wrong point object: %+v", o[0])
+		}
+		if !o[1].Ellipse || o[1].Width != 16 {
+			t.Errorf("wrong ellipse object: %+v", o[1])
+		}
+		if !reflect.DeepEqual(o[2].Polyline, []tiled.Point{{0, 0}, {8, 0}, {8, -4.5}}) {
+			t.Errorf("wrong polyline: %+v", o[2].Polyline)
+		}
+	}
+
+	if !reflect.DeepEqual(x, j) {
+		t.Errorf("XML and JSON maps differ")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTileset(t *testing.T) {
+	m, err := tiled.Open("testdata/map.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, id := m.Tileset(18)
+	if ts != m.Tilesets[1] || id != 1 {
+		t.Errorf("wrong tileset for GID 18: %v, %d", ts.Name, id)
+	}
+}
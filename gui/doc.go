@@ -0,0 +1,37 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+/*
+Package gui provides an immediate-mode user interface, drawn with the pixel
+package and driven by the default input actions.
+
+There is no widget tree to build or update: each frame, the interface is
+described by calling the widget methods in order, and each method returns
+whether the player interacted with it. Widgets are stacked vertically (or side
+by side, see UI.Columns), and any of them can receive the focus, either by
+pointing at it with the mouse, or by moving with the Up, Down, Left and Right
+actions (e.g. the keyboard arrows or a gamepad).
+
+	var ui gui.UI
+	var volume float32
+	var fullscreen bool
+
+	func (loop) React() {
+		ui.React()
+	}
+
+	func (loop) Render() {
+		ui.Begin(pixel.XY{16, 16}, 120)
+		ui.Label("Options")
+		ui.Slider("Volume", &volume, 0, 1, 0.1)
+		ui.Checkbox("Fullscreen", &fullscreen)
+		if ui.Button("Back") {
+			// ...
+		}
+		ui.End()
+	}
+
+The interface is drawn in canvas coordinates, at the layers given by its
+Style; it must not be described while a camera is in use.
+*/
+package gui
@@ -0,0 +1,480 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package gui
+
+import (
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/input"
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A UI holds the state of a user interface between frames: which widget has
+// the focus, the scrolling of lists and panels, and the input received since
+// the last frame. The zero value is ready to use, with the DefaultStyle.
+type UI struct {
+	Style Style
+
+	next Input // input received since the beginning of the frame
+	in   Input // input used by the widgets of the frame
+
+	// Layout
+	pos    pixel.XY // top-left corner of the next row
+	width  int16    // width of the rows
+	cols   int      // number of widgets in the current row
+	col    int      // position of the next widget in the current row
+	rowh   int16    // height of the current row
+	offset pixel.XY // translation from layout to canvas coordinates
+
+	// Focus
+	widgets []widget // focusable widgets of the frame, in order
+	focus   int      // index of the focused widget
+	pending int      // widget focused by the pointer, plus one (0 for none)
+	active  int      // widget captured by the pointer, plus one (0 for none)
+	used    direction
+
+	// Panels and lists
+	panels  []scroll    // scrolling of each panel, in order of declaration
+	npanels int         // number of panels declared during the frame
+	panel   int         // current panel, plus one (0 outside of panels)
+	nested  int         // depth of ignored panels (nesting is not supported)
+	saved   layout      // layout outside of the current panel
+	lists   map[int]int // first visible item of each list, by widget
+}
+
+// Input is the input of the interface for one frame.
+type Input struct {
+	Pointer      pixel.XY // position of the pointer, in canvas coordinates
+	PointerMoved bool
+	Click        bool // pointer button pressed
+	ClickHeld    bool // pointer button currently down
+	Select       bool
+	Up           bool
+	Down         bool
+	Left         bool
+	Right        bool
+	Scroll       int16 // wheel movement (positive for up)
+}
+
+// Style defines the appearance of the interface.
+type Style struct {
+	Font       pixel.FontID
+	Layer      int16 // layer of the backgrounds; text is drawn above
+	Text       color.Index
+	Background color.Index
+	Border     color.Index
+	Focus      color.Index // border of the focused widget
+	Accent     color.Index // checks, slider knobs and selections
+	Padding    int16       // between the border of a widget and its content
+	Spacing    int16       // between widgets
+}
+
+// DefaultStyle is used by interfaces with a zero Style. The colors are chosen
+// for the default palette of the pixel package.
+var DefaultStyle = Style{
+	Font:       pixel.Monozela10,
+	Layer:      0x7000,
+	Text:       7,  // White
+	Background: 1,  // Dark Blue
+	Border:     5,  // Dark Gray
+	Focus:      10, // Yellow
+	Accent:     12, // Blue
+	Padding:    2,
+	Spacing:    2,
+}
+
+// A widget is the area occupied by a focusable widget, in canvas coordinates
+// (max excluded).
+type widget struct {
+	min, max pixel.XY
+	panel    int // panel containing the widget, plus one
+}
+
+// A scroll is the vertical scrolling of a panel.
+type scroll struct {
+	offset   int16
+	content  int16 // height of the content, as of the last frame
+	min, max pixel.XY
+}
+
+type layout struct {
+	pos   pixel.XY
+	width int16
+}
+
+type direction uint8
+
+const (
+	up direction = 1 << iota
+	down
+	left
+	right
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// React reads the default input actions of the current device (Pointer,
+// Click, Select, Up, Down, Left and Right); it must be called from the React
+// method of the game loop.
+func (u *UI) React() {
+	p := pixel.XYof(input.Pointer.XY())
+	u.Feed(Input{
+		Pointer:      p,
+		PointerMoved: p != u.next.Pointer,
+		Click:        input.Click.Pressed(),
+		ClickHeld:    input.Click.Ongoing(),
+		Select:       input.Select.Pressed(),
+		Up:           input.Up.Pressed(),
+		Down:         input.Down.Pressed(),
+		Left:         input.Left.Pressed(),
+		Right:        input.Right.Pressed(),
+	})
+}
+
+// Feed adds some input to the next frame of the interface. It can be used
+// instead of React (or in addition to it), e.g. for custom actions, or to
+// scroll with the mouse wheel.
+func (u *UI) Feed(in Input) {
+	n := &u.next
+	n.Pointer = in.Pointer
+	n.PointerMoved = n.PointerMoved || in.PointerMoved
+	n.Click = n.Click || in.Click
+	n.ClickHeld = in.ClickHeld
+	n.Select = n.Select || in.Select
+	n.Up = n.Up || in.Up
+	n.Down = n.Down || in.Down
+	n.Left = n.Left || in.Left
+	n.Right = n.Right || in.Right
+	n.Scroll += in.Scroll
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Begin starts the description of the interface for the current frame. The
+// widgets are laid out from pos (the top-left corner), and are given the
+// specified width.
+func (u *UI) Begin(pos pixel.XY, width int16) {
+	if u.Style == (Style{}) {
+		u.Style = DefaultStyle
+	}
+	if u.lists == nil {
+		u.lists = map[int]int{}
+	}
+
+	u.in = u.next
+	u.next = Input{Pointer: u.in.Pointer, ClickHeld: u.in.ClickHeld}
+
+	u.pos = pos
+	u.width = width
+	u.cols, u.col, u.rowh = 1, 0, 0
+	u.offset = pixel.XY{}
+
+	u.widgets = u.widgets[:0]
+	u.pending = 0
+	u.used = 0
+	u.npanels, u.panel, u.nested = 0, 0, 0
+}
+
+// End finishes the description of the interface, and moves the focus according
+// to the input of the frame.
+func (u *UI) End() {
+	for u.panel != 0 {
+		u.EndPanel()
+	}
+	if !u.in.ClickHeld {
+		u.active = 0
+	}
+
+	if u.pending != 0 {
+		u.focus = u.pending - 1
+	}
+	if len(u.widgets) == 0 {
+		return
+	}
+	if u.focus >= len(u.widgets) {
+		u.focus = len(u.widgets) - 1
+	}
+	if u.focus < 0 {
+		u.focus = 0
+	}
+
+	for _, d := range []struct {
+		pressed bool
+		dir     direction
+	}{
+		{u.in.Up, up}, {u.in.Down, down}, {u.in.Left, left}, {u.in.Right, right},
+	} {
+		if d.pressed && u.used&d.dir == 0 {
+			u.move(d.dir)
+		}
+	}
+}
+
+// move gives the focus to the nearest widget in a direction, if there is one.
+// A widget qualifies only if it is entirely on that side of the focused one.
+//
+// Widgets of the same panel are preferred; for the others, the panel itself is
+// used in place of the widgets it contains (since they are scrolled), and the
+// visible ones are preferred.
+func (u *UI) move(d direction) {
+	f := u.widgets[u.focus]
+	best, cost := -1, [5]int32{}
+	for i, w := range u.widgets {
+		if i == u.focus {
+			continue
+		}
+		fr, wr := f.rect(), w.rect()
+		other := int32(0)
+		if w.panel != f.panel {
+			fr, wr = u.bounds(f), u.bounds(w)
+			other = 1
+		}
+		along, gap, center := distance(d, fr, wr)
+		if along < 0 {
+			continue
+		}
+		hidden := int32(0)
+		if !u.visible(w) {
+			hidden = 1
+		}
+		exact, _, _ := distance(d, f.rect(), w.rect())
+		c := [5]int32{other, along + gap, hidden, center, exact}
+		if best < 0 || less(c, cost) {
+			best, cost = i, c
+		}
+	}
+	if best < 0 {
+		return
+	}
+	u.focus = best
+	u.reveal(u.widgets[best])
+}
+
+// distance returns the distance between two areas along a direction (negative
+// if b is not entirely on that side of a), the gap between them on the other
+// axis (zero if they overlap), and the distance between their centers on the
+// other axis (doubled).
+func distance(d direction, a, b [4]int32) (along, gap, center int32) {
+	// Both areas are {min along, max along, min across, max across}, oriented
+	// so that the direction is toward higher values.
+	switch d {
+	case up:
+		a, b = [4]int32{-a[3], -a[1], a[0], a[2]}, [4]int32{-b[3], -b[1], b[0], b[2]}
+	case down:
+		a, b = [4]int32{a[1], a[3], a[0], a[2]}, [4]int32{b[1], b[3], b[0], b[2]}
+	case left:
+		a, b = [4]int32{-a[2], -a[0], a[1], a[3]}, [4]int32{-b[2], -b[0], b[1], b[3]}
+	case right:
+		a, b = [4]int32{a[0], a[2], a[1], a[3]}, [4]int32{b[0], b[2], b[1], b[3]}
+	}
+	along = b[0] - a[1]
+	if b[2] > a[3] {
+		gap = b[2] - a[3]
+	} else if a[2] > b[3] {
+		gap = a[2] - b[3]
+	}
+	center = b[2] + b[3] - a[2] - a[3]
+	if center < 0 {
+		center = -center
+	}
+	return along, gap, center
+}
+
+func less(a, b [5]int32) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// rect returns the area of a widget as {min.X, min.Y, max.X, max.Y}.
+func (w widget) rect() [4]int32 {
+	return [4]int32{int32(w.min.X), int32(w.min.Y), int32(w.max.X), int32(w.max.Y)}
+}
+
+// bounds returns the area of a widget, or of the panel containing it.
+func (u *UI) bounds(w widget) [4]int32 {
+	if w.panel == 0 {
+		return w.rect()
+	}
+	p := u.panels[w.panel-1]
+	return widget{min: p.min, max: p.max}.rect()
+}
+
+// visible returns true if a widget is not hidden by the scrolling of its panel.
+func (u *UI) visible(w widget) bool {
+	if w.panel == 0 {
+		return true
+	}
+	p := u.panels[w.panel-1]
+	return w.min.Y >= p.min.Y && w.max.Y <= p.max.Y
+}
+
+// reveal scrolls the panel containing a widget, so that it becomes visible.
+func (u *UI) reveal(w widget) {
+	if w.panel == 0 {
+		return
+	}
+	p := &u.panels[w.panel-1]
+	switch {
+	case w.max.Y-w.min.Y > p.max.Y-p.min.Y || w.min.Y < p.min.Y:
+		p.offset -= p.min.Y - w.min.Y
+	case w.max.Y > p.max.Y:
+		p.offset += w.max.Y - p.max.Y
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SetFocus gives the focus to a widget, designated by its position in the
+// description of the interface (counting only the focusable widgets, i.e. not
+// the labels). It takes effect on the next frame.
+func (u *UI) SetFocus(n int) {
+	u.focus = n
+	u.pending = 0
+}
+
+// Focused returns true if the last widget described has the focus.
+func (u *UI) Focused() bool {
+	return len(u.widgets) > 0 && u.focus == len(u.widgets)-1
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Columns lays out the next n widgets side by side, sharing the width of the
+// row. The following widgets are stacked vertically again.
+func (u *UI) Columns(n int) {
+	if u.col > 0 {
+		u.newline()
+	}
+	if n < 1 {
+		n = 1
+	}
+	u.cols = n
+}
+
+// Space adds an empty vertical space between widgets.
+func (u *UI) Space(h int16) {
+	if u.col > 0 {
+		u.newline()
+	}
+	u.pos.Y += h
+}
+
+// place reserves the area of the next widget (in layout coordinates), and
+// returns its corners (max excluded).
+func (u *UI) place(h int16) (min, max pixel.XY) {
+	w := u.columnWidth()
+	x := u.pos.X + u.width - w // last column is right-aligned
+	if u.col < u.cols-1 {
+		x = u.pos.X + int16(u.col)*(w+u.Style.Spacing)
+	}
+	if h > u.rowh {
+		u.rowh = h
+	}
+	u.col++
+	min, max = pixel.XY{x, u.pos.Y}, pixel.XY{x + w, u.pos.Y + h}
+	if u.col >= u.cols {
+		u.newline()
+	}
+	return min, max
+}
+
+// columnWidth returns the width of the next widget.
+func (u *UI) columnWidth() int16 {
+	n := int16(u.cols)
+	w := (u.width - (n-1)*u.Style.Spacing) / n
+	if u.col == u.cols-1 {
+		// The last column takes the remainder of the division
+		w = u.width - int16(u.col)*(w+u.Style.Spacing)
+	}
+	return w
+}
+
+func (u *UI) newline() {
+	u.pos.Y += u.rowh + u.Style.Spacing
+	u.cols, u.col, u.rowh = 1, 0, 0
+}
+
+// focusable registers a focusable widget occupying an area (in layout
+// coordinates), and returns its ID, whether it has the focus, and whether the
+// pointer is over it.
+func (u *UI) focusable(min, max pixel.XY) (id int, focused, hovered bool) {
+	id = len(u.widgets)
+	w := widget{
+		min:   min.Plus(u.offset),
+		max:   max.Plus(u.offset),
+		panel: u.panel,
+	}
+	u.widgets = append(u.widgets, w)
+	hovered = u.hovers(w.min, w.max)
+	if hovered && (u.in.PointerMoved || u.in.Click) && u.active == 0 {
+		u.pending = id + 1
+	}
+	return id, id == u.focus, hovered
+}
+
+// hovers returns true if the pointer is inside an area of the canvas, and
+// inside the current panel (if any).
+func (u *UI) hovers(min, max pixel.XY) bool {
+	p := u.in.Pointer
+	if u.panel != 0 {
+		c := u.panels[u.panel-1]
+		if p.X < c.min.X || p.Y < c.min.Y || p.X >= c.max.X || p.Y >= c.max.Y {
+			return false
+		}
+	}
+	return p.X >= min.X && p.Y >= min.Y && p.X < max.X && p.Y < max.Y
+}
+
+// capture makes a widget receive the pointer until the button is released.
+func (u *UI) capture(id int) {
+	u.active = id + 1
+}
+
+// captured returns true if a widget receives the pointer.
+func (u *UI) captured(id int) bool {
+	return u.active == id+1 && u.in.ClickHeld
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// lineHeight returns the height of a single-line widget.
+func (u *UI) lineHeight() int16 {
+	h := u.Style.Font.Height()
+	if h < 1 {
+		h = 1
+	}
+	return h + 2*u.Style.Padding
+}
+
+// frame draws the background and border of a widget.
+func (u *UI) frame(min, max pixel.XY, focused bool) {
+	b := u.Style.Border
+	l := u.Style.Layer
+	if focused {
+		b = u.Style.Focus
+		l++
+	}
+	pixel.Box(b, u.Style.Background, l, 0, min, max.Minus(pixel.XY{1, 1}))
+}
+
+// text draws a single line of text, vertically centered in an area.
+func (u *UI) text(s string, min, max pixel.XY, align pixel.Alignment) {
+	c := u.cursor()
+	f := u.Style.Font
+	x := min.X + u.Style.Padding
+	switch align {
+	case pixel.AlignCenter:
+		x = (min.X + max.X - c.Measure(s).X) / 2
+	case pixel.AlignRight:
+		x = max.X - u.Style.Padding - c.Measure(s).X
+	}
+	y := (min.Y+max.Y-f.Height())/2 + f.Baseline()
+	c.Locate(u.Style.Layer+2, pixel.XY{x, y})
+	c.Print(s)
+}
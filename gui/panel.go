@@ -0,0 +1,103 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package gui
+
+import (
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BeginPanel starts a scrolling panel of the specified height. The widgets
+// described until EndPanel are laid out inside it, and clipped to its area.
+// The panel scrolls to reveal the widget receiving the focus, and with the
+// wheel (see Input.Scroll) when the pointer is over it.
+//
+// Panels cannot be nested: the widgets of an inner panel are laid out as part
+// of the outer one.
+func (u *UI) BeginPanel(height int16) {
+	if u.panel != 0 {
+		u.nested++
+		return
+	}
+	if u.col > 0 {
+		u.newline()
+	}
+	min, max := u.place(height)
+	pixel.Box(u.Style.Border, u.Style.Background, u.Style.Layer, 0, min, max.Minus(pixel.XY{1, 1}))
+
+	if u.npanels >= len(u.panels) {
+		u.panels = append(u.panels, scroll{})
+	}
+	u.npanels++
+	u.panel = u.npanels
+	p := &u.panels[u.panel-1]
+	p.min, p.max = min.Plus(pixel.XY{1, 1}), max.Minus(pixel.XY{1, 1})
+	p.clamp()
+
+	pad := u.Style.Padding
+	u.saved = layout{pos: u.pos, width: u.width}
+	u.pos = p.min.Plus(pixel.XY{pad, pad})
+	u.width = p.max.X - p.min.X - 2*pad - 3 // room for the scroll bar
+	u.offset = pixel.XY{0, -p.offset}
+
+	c := pixel.Camera{
+		Position: p.min,
+		Size:     p.max.Minus(p.min),
+		Scroll:   p.min.Plus(pixel.XY{0, p.offset}),
+	}
+	c.Use(1, 0)
+}
+
+// EndPanel finishes the current panel.
+func (u *UI) EndPanel() {
+	if u.nested > 0 {
+		u.nested--
+		return
+	}
+	if u.panel == 0 {
+		return
+	}
+	if u.col > 0 {
+		u.newline()
+	}
+	pixel.ResetCamera()
+
+	p := &u.panels[u.panel-1]
+	p.content = u.pos.Y - u.Style.Spacing + u.Style.Padding - p.min.Y
+	if u.in.Scroll != 0 && u.hovers(p.min, p.max) {
+		p.offset -= u.in.Scroll * u.lineHeight()
+		u.in.Scroll = 0
+	}
+	p.clamp()
+
+	if v := p.max.Y - p.min.Y; p.content > v {
+		h := int16(int32(v) * int32(v) / int32(p.content))
+		y := p.min.Y + int16(int32(p.offset)*int32(v)/int32(p.content))
+		pixel.Box(u.Style.Border, u.Style.Border, u.Style.Layer+1, 0,
+			pixel.XY{p.max.X - 2, y}, pixel.XY{p.max.X - 1, y + h - 1})
+	}
+
+	u.pos, u.width = u.saved.pos, u.saved.width
+	u.offset = pixel.XY{}
+	u.panel = 0
+}
+
+// Scroll returns the vertical scrolling of the last panel described.
+func (u *UI) Scroll() int16 {
+	if u.npanels == 0 {
+		return 0
+	}
+	return u.panels[u.npanels-1].offset
+}
+
+// clamp keeps the scrolling inside the content of the panel.
+func (p *scroll) clamp() {
+	if m := p.content - (p.max.Y - p.min.Y); p.offset > m {
+		p.offset = m
+	}
+	if p.offset < 0 {
+		p.offset = 0
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package gui
+
+import (
+	"math"
+
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Label displays a text, wrapped to the width of the widget. It cannot receive
+// the focus.
+func (u *UI) Label(text string) {
+	c := u.cursor()
+	p := u.Style.Padding
+	w := u.columnWidth() - 2*p
+	lines := c.Wrap(w, text)
+	h := u.Style.Font.Height() + int16(len(lines)-1)*c.Interline
+	min, _ := u.place(h + 2*p)
+	c.Locate(u.Style.Layer+2, pixel.XY{min.X + p, min.Y + p + u.Style.Font.Baseline()})
+	c.PrintBox(w, pixel.AlignLeft, text)
+}
+
+// Button displays a button, and returns true if it has been activated, either
+// by a click or by the Select action while it has the focus.
+func (u *UI) Button(label string) bool {
+	min, max := u.place(u.lineHeight())
+	_, focused, hovered := u.focusable(min, max)
+	activated := focused && u.in.Select || hovered && u.in.Click
+
+	u.frame(min, max, focused)
+	if activated {
+		a := u.Style.Accent
+		pixel.Box(a, a, u.Style.Layer+1, 0, min.Plus(pixel.XY{1, 1}), max.Minus(pixel.XY{2, 2}))
+	}
+	u.text(label, min, max, pixel.AlignCenter)
+	return activated
+}
+
+// Checkbox displays a box that can be checked or unchecked, followed by a
+// label. It returns true if the value has been changed.
+func (u *UI) Checkbox(label string, value *bool) bool {
+	min, max := u.place(u.lineHeight())
+	_, focused, hovered := u.focusable(min, max)
+	changed := focused && u.in.Select || hovered && u.in.Click
+	if changed {
+		*value = !*value
+	}
+
+	u.frame(min, max, focused)
+	p := u.Style.Padding
+	s := max.Y - min.Y - 2*p
+	b1 := pixel.XY{min.X + p, min.Y + p}
+	b2 := b1.Plus(pixel.XY{s - 1, s - 1})
+	pixel.Box(u.Style.Border, u.Style.Background, u.Style.Layer+1, 0, b1, b2)
+	if *value && s > 4 {
+		a := u.Style.Accent
+		pixel.Box(a, a, u.Style.Layer+2, 0, b1.Plus(pixel.XY{2, 2}), b2.Minus(pixel.XY{2, 2}))
+	}
+	u.text(label, pixel.XY{b2.X + 1, min.Y}, max, pixel.AlignLeft)
+	return changed
+}
+
+// Slider displays a label followed by a horizontal slider, to choose a value
+// between min and max. When the slider has the focus, the Left and Right
+// actions change the value by step; it can also be dragged with the pointer.
+// If step is positive, the value is always a multiple of step (starting from
+// min). Slider returns true if the value has been changed.
+func (u *UI) Slider(label string, value *float32, min, max, step float32) bool {
+	p1, p2 := u.place(u.lineHeight())
+	id, focused, hovered := u.focusable(p1, p2)
+	old := *value
+
+	p := u.Style.Padding
+	t1 := pixel.XY{(p1.X + p2.X) / 2, p1.Y + p} // track
+	t2 := pixel.XY{p2.X - p - 1, p2.Y - p - 1}  // (corners included)
+	k := (t2.Y - t1.Y + 2) / 2                  // width of the knob
+	r := float32(t2.X - t1.X + 1 - k)           // range of the knob
+	if hovered && u.in.Click {
+		u.capture(id)
+	}
+	if u.captured(id) && r > 0 && max > min {
+		x := u.in.Pointer.Plus(u.panelOffset()).X - t1.X - k/2
+		*value = min + float32(x)/r*(max-min)
+	}
+	if focused {
+		u.used |= left | right
+		if u.in.Left {
+			*value -= step
+		}
+		if u.in.Right {
+			*value += step
+		}
+	}
+	if step > 0 {
+		*value = min + step*float32(math.Floor(float64((*value-min)/step)+0.5))
+	}
+	if *value < min {
+		*value = min
+	}
+	if *value > max {
+		*value = max
+	}
+
+	u.frame(p1, p2, focused)
+	u.text(label, p1, pixel.XY{t1.X, p2.Y}, pixel.AlignLeft)
+	m := (t1.Y + t2.Y) / 2
+	pixel.Box(u.Style.Border, u.Style.Border, u.Style.Layer+1, 0,
+		pixel.XY{t1.X, m}, pixel.XY{t2.X, m})
+	x := t1.X
+	if max > min {
+		x += int16((*value - min) / (max - min) * r)
+	}
+	a := u.Style.Accent
+	pixel.Box(a, a, u.Style.Layer+2, 0, pixel.XY{x, t1.Y}, pixel.XY{x + k - 1, t2.Y})
+
+	return *value != old
+}
+
+// List displays a list of items, with the specified number of rows visible at
+// once, and a highlight on the selected one. When the list has the focus, the
+// Up and Down actions change the selection (the focus only leaves the list past
+// its first and last items); an item can also be selected with a click. List
+// returns true if the selection has been changed.
+func (u *UI) List(selected *int, items []string, rows int) bool {
+	if rows < 1 {
+		rows = 1
+	}
+	p := u.Style.Padding
+	ih := u.Style.Font.Height() + p // height of an item
+	if ih < 1 {
+		ih = 1
+	}
+	min, max := u.place(int16(rows)*ih + 2*p)
+	id, focused, hovered := u.focusable(min, max)
+	old := *selected
+	n := len(items)
+	first := u.lists[id]
+
+	if focused {
+		if *selected > 0 {
+			u.used |= up
+			if u.in.Up {
+				*selected--
+			}
+		}
+		if *selected < n-1 {
+			u.used |= down
+			if u.in.Down {
+				*selected++
+			}
+		}
+	}
+	if *selected >= n {
+		*selected = n - 1
+	}
+	if *selected < 0 && n > 0 {
+		*selected = 0
+	}
+	if *selected != old {
+		// Keep the selection visible
+		if *selected < first {
+			first = *selected
+		}
+		if *selected >= first+rows {
+			first = *selected - rows + 1
+		}
+	}
+	if hovered {
+		if u.in.Click {
+			i := first + int((u.in.Pointer.Y+u.panelOffset().Y-min.Y-p)/ih)
+			if i >= first && i < n && i < first+rows {
+				*selected = i
+			}
+		}
+		first -= int(u.in.Scroll)
+		u.in.Scroll = 0
+	}
+	if first > n-rows {
+		first = n - rows
+	}
+	if first < 0 {
+		first = 0
+	}
+	u.lists[id] = first
+
+	u.frame(min, max, focused)
+	right := max.X
+	if n > rows {
+		// Scroll bar
+		right = max.X - p - 2
+		t := int16(rows) * ih
+		h := int16(int32(t) * int32(rows) / int32(n))
+		y := min.Y + p + int16(int32(t)*int32(first)/int32(n))
+		pixel.Box(u.Style.Border, u.Style.Border, u.Style.Layer+1, 0,
+			pixel.XY{right, y}, pixel.XY{right + 1, y + h - 1})
+	}
+	for i := first; i < n && i < first+rows; i++ {
+		y := min.Y + p + int16(i-first)*ih
+		if i == *selected {
+			a := u.Style.Accent
+			pixel.Box(a, a, u.Style.Layer+1, 0,
+				pixel.XY{min.X + p, y}, pixel.XY{right - p - 1, y + ih - 1})
+		}
+		u.text(items[i], pixel.XY{min.X, y}, pixel.XY{right, y + ih}, pixel.AlignLeft)
+	}
+
+	return *selected != old
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// cursor returns a text cursor with the style of the interface.
+func (u *UI) cursor() pixel.Cursor {
+	c := pixel.Cursor{}
+	c.Style(u.Style.Text, u.Style.Font)
+	return c
+}
+
+// panelOffset returns the translation from canvas to layout coordinates.
+func (u *UI) panelOffset() pixel.XY {
+	return pixel.XY{}.Minus(u.offset)
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package gui_test
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/gui"
+	"github.com/cozely/cozely/internal"
+	"github.com/cozely/cozely/pixel"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Without a running framework, the font has a null height: all single-line
+// widgets are 5 pixels high (with the padding), separated by 2 pixels.
+
+func TestNavigation(t *testing.T) {
+	internal.Config.Headless = true
+	defer func() { internal.Config.Headless = false }()
+
+	var ui gui.UI
+	var focused [4]bool
+	var pressed [4]bool
+	frame := func(in gui.Input) {
+		ui.Feed(in)
+		ui.Begin(pixel.XY{10, 10}, 100)
+		pressed[0] = ui.Button("One")
+		focused[0] = ui.Focused()
+		ui.Columns(2)
+		pressed[1] = ui.Button("Two")
+		focused[1] = ui.Focused()
+		pressed[2] = ui.Button("Three")
+		focused[2] = ui.Focused()
+		pressed[3] = ui.Button("Four")
+		focused[3] = ui.Focused()
+		ui.End()
+	}
+	is := func(n int) bool {
+		for i, f := range focused {
+			if f != (i == n) {
+				return false
+			}
+		}
+		return true
+	}
+
+	frame(gui.Input{})
+	if !is(0) {
+		t.Errorf("first widget not focused initially: %v", focused)
+	}
+	frame(gui.Input{Down: true})
+	frame(gui.Input{})
+	if !is(1) {
+		t.Errorf("down: wrong focus %v", focused)
+	}
+	frame(gui.Input{Right: true})
+	frame(gui.Input{Select: true})
+	if !is(2) || !pressed[2] || pressed[1] {
+		t.Errorf("right and select: focus %v, pressed %v", focused, pressed)
+	}
+	frame(gui.Input{Down: true})
+	frame(gui.Input{})
+	if !is(3) {
+		t.Errorf("down from column: wrong focus %v", focused)
+	}
+	frame(gui.Input{Down: true})
+	frame(gui.Input{})
+	if !is(3) {
+		t.Errorf("down from last: wrong focus %v", focused)
+	}
+	frame(gui.Input{Up: true})
+	frame(gui.Input{Up: true})
+	frame(gui.Input{})
+	if !is(0) {
+		t.Errorf("up twice: wrong focus %v", focused)
+	}
+
+	// Mouse
+
+	frame(gui.Input{Pointer: pixel.XY{100, 18}, PointerMoved: true})
+	frame(gui.Input{})
+	if !is(2) {
+		t.Errorf("pointer: wrong focus %v", focused)
+	}
+	frame(gui.Input{Pointer: pixel.XY{20, 12}, Click: true})
+	if !pressed[0] {
+		t.Errorf("click: button not pressed")
+	}
+	frame(gui.Input{})
+	if !is(0) {
+		t.Errorf("click: wrong focus %v", focused)
+	}
+
+	// Out of range focus
+
+	ui.SetFocus(-1)
+	frame(gui.Input{Down: true})
+	frame(gui.Input{})
+	if !is(1) {
+		t.Errorf("down from negative focus: wrong focus %v", focused)
+	}
+}
+
+func TestWidgets(t *testing.T) {
+	internal.Config.Headless = true
+	defer func() { internal.Config.Headless = false }()
+
+	var ui gui.UI
+	check := false
+	volume := float32(0.5)
+	selected := 0
+	items := []string{"a", "b", "c", "d", "e"}
+	var changed [3]bool
+	frame := func(in gui.Input) {
+		ui.Feed(in)
+		ui.Begin(pixel.XY{0, 0}, 100)
+		ui.Label("Options")
+		changed[0] = ui.Checkbox("Check", &check)
+		changed[1] = ui.Slider("Volume", &volume, 0, 1, 0.25)
+		changed[2] = ui.List(&selected, items, 3)
+		ui.End()
+	}
+
+	frame(gui.Input{Select: true})
+	if !check || !changed[0] {
+		t.Errorf("checkbox not checked")
+	}
+	frame(gui.Input{Pointer: pixel.XY{3, 9}, Click: true})
+	if check || !changed[0] {
+		t.Errorf("checkbox not unchecked by click")
+	}
+
+	frame(gui.Input{Down: true})
+	frame(gui.Input{Right: true})
+	if volume != 0.75 || !changed[1] {
+		t.Errorf("slider right: %v", volume)
+	}
+	frame(gui.Input{Right: true})
+	frame(gui.Input{Right: true})
+	if volume != 1 || changed[1] {
+		t.Errorf("slider clamp: %v", volume)
+	}
+	frame(gui.Input{Left: true})
+	if volume != 0.75 {
+		t.Errorf("slider left: %v", volume)
+	}
+	// Slider: track from x=50 to 97, knob 1 pixel wide
+	frame(gui.Input{Pointer: pixel.XY{50, 16}, Click: true, ClickHeld: true})
+	if volume != 0 {
+		t.Errorf("slider click: %v", volume)
+	}
+	frame(gui.Input{Pointer: pixel.XY{200, 0}, ClickHeld: true})
+	if volume != 1 {
+		t.Errorf("slider drag: %v", volume)
+	}
+
+	// List: the label is 4 pixels high (empty line), so the list starts at
+	// y=20, and its rows are 2 pixels high (empty line plus padding)
+
+	frame(gui.Input{Down: true})
+	for i := 0; i < 3; i++ {
+		frame(gui.Input{Down: true})
+	}
+	if selected != 3 {
+		t.Errorf("list down: selection %d", selected)
+	}
+	frame(gui.Input{Up: true})
+	if selected != 2 || !changed[2] {
+		t.Errorf("list up: selection %d", selected)
+	}
+	frame(gui.Input{Pointer: pixel.XY{20, 22}, Click: true})
+	if selected != 1 {
+		t.Errorf("list click: selection %d", selected)
+	}
+	frame(gui.Input{Up: true})
+	frame(gui.Input{Up: true})
+	frame(gui.Input{Left: true})
+	if volume != 0.75 {
+		t.Errorf("focus did not leave the list")
+	}
+}
+
+func TestPanel(t *testing.T) {
+	internal.Config.Headless = true
+	defer func() { internal.Config.Headless = false }()
+
+	var ui gui.UI
+	var last bool
+	var scroll int16
+	frame := func(in gui.Input) {
+		ui.Feed(in)
+		ui.Begin(pixel.XY{0, 0}, 100)
+		ui.BeginPanel(20)
+		for i := 0; i < 10; i++ {
+			ui.Button("Item")
+		}
+		last = ui.Focused()
+		ui.EndPanel()
+		scroll = ui.Scroll()
+		ui.Button("After")
+		ui.End()
+	}
+
+	frame(gui.Input{})
+	for i := 0; i < 9; i++ {
+		frame(gui.Input{Down: true})
+	}
+	frame(gui.Input{})
+	// The last button is at y=66-71 (without scrolling), and the panel area at
+	// y=1-19
+	if !last || scroll != 71-19 {
+		t.Errorf("panel did not scroll to the focus: %d", scroll)
+	}
+	frame(gui.Input{Pointer: pixel.XY{10, 10}, Scroll: 2})
+	frame(gui.Input{})
+	if scroll != 71-19-10 {
+		t.Errorf("panel did not scroll with the wheel: %d", scroll)
+	}
+	frame(gui.Input{Down: true})
+	frame(gui.Input{})
+	if last {
+		t.Errorf("focus did not leave the panel")
+	}
+}
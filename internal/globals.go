@@ -36,6 +36,7 @@ var Config = struct {
 	Fullscreen     bool
 	FullscreenMode string
 	VSync          bool
+	Headless       bool
 }{
 	Debug:          false,
 	WindowSize:     [2]int16{1280, 720},
@@ -43,6 +44,7 @@ var Config = struct {
 	Fullscreen:     false,
 	FullscreenMode: "Desktop",
 	VSync:          true,
+	Headless:       false,
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -71,6 +73,15 @@ var (
 	UpdateLag float64
 )
 
+var (
+	// ReactTime and UpdateTime are the time spent in the game loop methods during
+	// the current frame (they can be called several times per frame).
+	ReactTime, UpdateTime float64
+	// RenderTime is the time spent rendering the previous frame (including the
+	// execution of the drawing commands).
+	RenderTime float64
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // QuitRequested makes the game loop stop if true.
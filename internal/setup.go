@@ -67,9 +67,15 @@ func Setup() error {
 
 	// Initialize SDL
 
-	if errcode := C.SDL_Init(C.SDL_INIT_VIDEO |
+	var flags C.Uint32 = C.SDL_INIT_VIDEO |
 		C.SDL_INIT_JOYSTICK |
-		C.SDL_INIT_GAMECONTROLLER); errcode != 0 {
+		C.SDL_INIT_GAMECONTROLLER
+	if Config.Headless {
+		// Neither window nor OpenGL context, only the event queue
+		flags = C.SDL_INIT_EVENTS
+	}
+
+	if errcode := C.SDL_Init(flags); errcode != 0 {
 		return Wrap("in SDL initialization", GetSDLError())
 	}
 
@@ -85,6 +91,11 @@ func Setup() error {
 
 	getKeyboardArray()
 
+	if Config.Headless {
+		Window.Width, Window.Height = Config.WindowSize[0], Config.WindowSize[1]
+		return nil
+	}
+
 	// Open the window
 
 	err = OpenWindow(
@@ -104,7 +115,9 @@ func Setup() error {
 }
 
 func Cleanup() error {
-	destroyWindow()
+	if !Config.Headless {
+		destroyWindow()
+	}
 	SDLQuit()
 	return nil
 }
@@ -143,6 +143,9 @@ func sdlGLAttribute(attr C.SDL_GLattr) (int, error) {
 
 // SwapWindow swaps the double-buffer.
 func SwapWindow() {
+	if Window.window == nil {
+		return
+	}
 	C.SwapWindow(Window.window)
 }
 
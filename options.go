@@ -66,3 +66,22 @@ func Multisample(s int32) Option {
 }
 
 ////////////////////////////////////////////////////////////////////////////////
+
+// Headless makes the framework run without window nor OpenGL context. Only the
+// pixel package is supported in this mode: it uses a software renderer instead
+// of the GPU, which allows to run (and test) the game on machines without
+// graphics hardware.
+//
+// The size of the canvas is then computed from the "WindowSize" entry of the
+// configuration file.
+func Headless(h bool) Option {
+	return func() error {
+		if internal.Running {
+			return errors.New("cannot change headless mode while running")
+		}
+		internal.Config.Headless = h
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A LoopMode specifies how an animation behaves once its last frame has been
+// displayed.
+type LoopMode uint8
+
+// Available loop modes.
+const (
+	Loop     LoopMode = iota // start again from the first frame
+	Once                     // stay on the last frame
+	PingPong                 // play backward, then forward again, and so on
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// An Animation is a sequence of frames from a sprite sheet. It does not hold
+// any state: the frame displayed only depends on the time given to Paint, so
+// the same animation can be used for any number of sprites.
+type Animation struct {
+	Mode LoopMode
+
+	sheet    SheetID
+	tag      string
+	from, to int16
+	duration float64 // duration of each frame (0 for the sheet durations)
+}
+
+// Animation returns an animation made of the frames from first to last
+// (included), each of them displayed for duration seconds.
+func (a SheetID) Animation(first, last int, duration float64, mode LoopMode) Animation {
+	return Animation{
+		Mode:     mode,
+		sheet:    a,
+		from:     int16(first),
+		to:       int16(last),
+		duration: duration,
+	}
+}
+
+// Tag returns the animation corresponding to a tag of the sheet JSON
+// description, using the frame durations of the description. The direction of
+// the tag is respected: "reverse" plays the frames backward, and "pingpong"
+// uses the PingPong mode (unless Mode is changed to Once).
+//
+// The tag is only looked up when the animation is used, so this method can be
+// called before the sheet is loaded.
+func (a SheetID) Tag(name string) Animation {
+	return Animation{sheet: a, tag: name}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Paint displays the frame of the animation corresponding to time t, in
+// seconds since the start of the animation (usually computed from
+// cozely.GameTime).
+func (an Animation) Paint(layer int16, pos XY, t float64) {
+	p := an.Frame(t)
+	if p == noPicture {
+		return
+	}
+	p.Paint(layer, pos)
+}
+
+// Frame returns the picture displayed at time t, in seconds since the start of
+// the animation.
+func (an Animation) Frame(t float64) PictureID {
+	from, n, l, reverse := an.sequence()
+	if n == 0 {
+		return noPicture
+	}
+
+	total := an.Duration()
+	k := 0
+	switch {
+	case total <= 0 || t <= 0:
+	case an.mode() == Once && t >= total:
+		k = l - 1
+	default:
+		t = math.Mod(t, total)
+		for k < l-1 {
+			t -= an.frameDuration(from + step(k, n, reverse))
+			if t < 0 {
+				break
+			}
+			k++
+		}
+	}
+
+	return PictureID(int(sheets[an.sheet].first) + from + step(k, n, reverse))
+}
+
+// Duration returns the time needed to play the animation once (for the
+// PingPong mode, a complete back and forth).
+func (an Animation) Duration() float64 {
+	from, n, l, _ := an.sequence()
+	d := 0.0
+	for k := 0; k < l; k++ {
+		d += an.frameDuration(from + step(k, n, false))
+	}
+	return d
+}
+
+// sequence returns the first frame of the animation, its number of frames n,
+// and the length l of the sequence (which is longer than n in PingPong mode).
+// They are all zero if the animation is invalid.
+func (an Animation) sequence() (from, n, l int, reverse bool) {
+	if int(an.sheet) >= len(sheets) {
+		return 0, 0, 0, false
+	}
+	s := &sheets[an.sheet]
+	f, t := an.from, an.to
+	if an.tag != "" {
+		st, ok := s.tags[an.tag]
+		if !ok {
+			return 0, 0, 0, false
+		}
+		f, t, reverse = st.from, st.to, st.reverse
+	}
+	if f < 0 || t < f || int(t) >= int(s.count) {
+		return 0, 0, 0, false
+	}
+
+	n = int(t-f) + 1
+	l = n
+	if an.mode() == PingPong && n > 1 {
+		l = 2*n - 2
+	}
+	return int(f), n, l, reverse
+}
+
+// mode returns the loop mode of the animation, taking the tag into account.
+func (an Animation) mode() LoopMode {
+	if an.tag != "" && an.Mode == Loop && int(an.sheet) < len(sheets) {
+		return sheets[an.sheet].tags[an.tag].mode
+	}
+	return an.Mode
+}
+
+func (an Animation) frameDuration(frame int) float64 {
+	if an.duration == 0 && int(an.sheet) < len(sheets) && sheets[an.sheet].durations != nil {
+		return sheets[an.sheet].durations[frame]
+	}
+	return an.duration
+}
+
+// step returns the frame displayed at step k of a sequence of n frames (going
+// forward then, in PingPong mode, backward).
+func step(k, n int, reverse bool) int {
+	i := k
+	if k >= n {
+		i = 2*n - 2 - k
+	}
+	if reverse {
+		i = n - 1 - i
+	}
+	return i
+}
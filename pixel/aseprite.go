@@ -0,0 +1,142 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+	"image"
+	"io/fs"
+	"strings"
+
+	"github.com/cozely/cozely/formats/aseprite"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// isAseprite returns true if the path of a picture or sheet designates an
+// Aseprite file (rather than a PNG file without its extension).
+func isAseprite(path string) bool {
+	return strings.HasSuffix(path, ".aseprite") || strings.HasSuffix(path, ".ase")
+}
+
+func readAseprite(fsys fs.FS, path string) (*aseprite.File, error) {
+	f, err := open(fsys, path)
+	if err != nil {
+		return nil, internal.Wrap(`while opening Aseprite file "`+path+`"`, err)
+	}
+	defer f.Close()
+
+	a, err := aseprite.Read(f)
+	if err != nil {
+		return nil, internal.Wrap(`while decoding Aseprite file "`+path+`"`, err)
+	}
+	if len(a.Frames) == 0 {
+		return nil, errors.New(`no frames in Aseprite file "` + path + `"`)
+	}
+	return a, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// loadAseprite loads a sheet from an Aseprite file: a picture for each frame,
+// followed by a picture for each slice.
+func (a SheetID) loadAseprite() error {
+	s := &sheets[a]
+	path := sheetPaths[a]
+	if s.size.X != 0 {
+		return errors.New(`impossible to load sheet "` + path + `" (Aseprite sheets cannot be grids)`)
+	}
+
+	f, err := readAseprite(s.fsys, path)
+	if err != nil {
+		return err
+	}
+
+	// Everything is validated before creating the pictures
+
+	tags := make(map[string]sheetTag, len(f.Tags))
+	for _, t := range f.Tags {
+		if t.From < 0 || t.To < t.From || t.To >= len(f.Frames) {
+			return errors.New(`invalid frame range for tag "` + t.Name + `" in ` + path)
+		}
+		st := sheetTag{from: int16(t.From), to: int16(t.To)}
+		switch t.Direction {
+		case aseprite.Reverse:
+			st.reverse = true
+		case aseprite.PingPong:
+			st.mode = PingPong
+		case aseprite.PingPongReverse:
+			st.mode = PingPong
+			st.reverse = true
+		}
+		tags[t.Name] = st
+	}
+
+	frames := make([]*image.Paletted, len(f.Frames))
+	for i := range f.Frames {
+		frames[i] = indexed(f.Image(i), s.colors)
+	}
+
+	// Slices are cut from the frame of their first key
+
+	type slice struct {
+		name   string
+		image  *image.Paletted
+		center image.Rectangle
+	}
+	slices := make([]slice, 0, len(f.Slices))
+	for _, sl := range f.Slices {
+		if len(sl.Keys) == 0 {
+			continue
+		}
+		k := sl.Keys[0]
+		m := frames[0]
+		if k.Frame < len(frames) {
+			m = frames[k.Frame]
+		}
+		r := k.Bounds.Intersect(m.Bounds())
+		if r.Empty() {
+			return errors.New(`slice "` + sl.Name + `" outside of the sprite in ` + path)
+		}
+		c := k.Center.Add(k.Bounds.Min).Intersect(r).Sub(r.Min)
+		if c.Empty() {
+			c = image.Rectangle{Max: r.Size()}
+		}
+		slices = append(slices, slice{sl.Name, m.SubImage(r).(*image.Paletted), c})
+	}
+
+	// Pictures
+
+	s.first = uint16(len(pictures.mapping))
+	s.durations = make([]float64, len(f.Frames))
+	for i := range frames {
+		if picture(frames[i]) == noPicture {
+			return errors.New(`impossible to load sheet "` + path + `" (too many pictures)`)
+		}
+		s.durations[i] = float64(f.Frames[i].Duration) / 1000
+	}
+	s.count = uint16(len(f.Frames))
+	s.tags = tags
+
+	s.names = make(map[string]uint16, len(slices))
+	s.centers = make(map[string][2]XY, len(slices))
+	for _, sl := range slices {
+		p := picture(sl.image)
+		if p == noPicture {
+			return errors.New(`impossible to load sheet "` + path + `" (too many pictures)`)
+		}
+		s.names[sl.name] = uint16(p) - s.first
+		s.centers[sl.name] = [2]XY{
+			{int16(sl.center.Min.X), int16(sl.center.Min.Y)},
+			{int16(sl.center.Max.X), int16(sl.center.Max.Y)},
+		}
+	}
+
+	s.loaded = true
+
+	internal.Debug.Printf("Loaded sheet %s (%d frames, %d slices)", path, s.count, len(s.names))
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestAseprite(t *testing.T) {
+	internal.Config.Headless = true
+	defer func() { internal.Config.Headless = false }()
+
+	// A 4x3 sprite with 2 frames (of 100 and 200ms), a "blink" pingpong tag, a
+	// "back" reverse tag, and a "panel" nine-patch slice
+	data, err := os.ReadFile("../formats/aseprite/testdata/sprite.aseprite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"sprite.aseprite": {Data: data}}
+
+	pict := PictureFS(fsys, "sprite.aseprite")
+	sheet := SheetFS(fsys, "sprite.aseprite", XY{})
+
+	if err := setup(); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	if pict.Size() != (XY{4, 3}) {
+		t.Errorf("picture: want size {4 3}, got %v", pict.Size())
+	}
+
+	if sheet.FrameCount() != 2 {
+		t.Fatalf("frame count: want 2, got %d", sheet.FrameCount())
+	}
+	for i, want := range [][]uint8{
+		{1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1},
+		{3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	} {
+		if got := pictures.image[sheet.Frame(i)].Pix; !reflect.DeepEqual(got, want) {
+			t.Errorf("frame %d: want %v, got %v", i, want, got)
+		}
+	}
+
+	for _, a := range []struct {
+		name   string
+		times  []float64
+		frames []int
+	}{
+		{"blink", []float64{0, 0.05, 0.1, 0.25, 0.32, 0.35}, []int{0, 0, 1, 1, 0, 0}},
+		{"back", []float64{0, 0.15, 0.2, 0.25}, []int{1, 1, 0, 0}},
+	} {
+		for i, tm := range a.times {
+			if p := sheet.Tag(a.name).Frame(tm); p != sheet.Frame(a.frames[i]) {
+				t.Errorf("tag %s at %v: want frame %d, got picture %d", a.name, tm, a.frames[i], p)
+			}
+		}
+	}
+
+	n := sheet.NineSlice("panel")
+	if n.Picture == noPicture || n.Picture.Size() != (XY{4, 3}) ||
+		n.Min != (XY{1, 1}) || n.Max != (XY{3, 2}) {
+		t.Errorf("nine-slice: got %+v", n)
+	}
+	if sheet.NineSlice("nope").Picture != noPicture {
+		t.Errorf("missing slice should be noPicture")
+	}
+
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAsepriteInvalidTag(t *testing.T) {
+	defer headless(t, XY{1, 1})()
+
+	data, err := os.ReadFile("../formats/aseprite/testdata/sprite.aseprite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Move the end of the "blink" tag past the last frame
+	i := bytes.Index(data, []byte("\x05\x00blink"))
+	if i < 17 {
+		t.Fatal("tag not found in test file")
+	}
+	data = append([]byte(nil), data...)
+	data[i-15] = 9
+
+	n := len(pictures.mapping)
+	s := SheetFS(fstest.MapFS{"bad.aseprite": {Data: data}}, "bad.aseprite", XY{})
+	if err := s.load(); err == nil {
+		t.Errorf("invalid tag range accepted")
+	}
+	if len(pictures.mapping) != n || s.FrameCount() != 0 {
+		t.Errorf("invalid sheet: %d pictures created, %d frames",
+			len(pictures.mapping)-n, s.FrameCount())
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+	"image"
+	stdcolor "image/color"
+	"path"
+	"sort"
+
+	"github.com/cozely/cozely/formats/bdf"
+	"github.com/cozely/cozely/formats/bmfont"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A fontGlyph is a glyph of a BMFont or BDF font, before its conversion into a
+// picture.
+type fontGlyph struct {
+	rune    rune
+	image   image.Image
+	rect    image.Rectangle // area of the glyph in the image
+	x, y    int             // position relative to the cursor, at the top of the line
+	advance int
+}
+
+func (f FontID) loadBMFont() error {
+	p := fontPaths[f]
+	fl, err := open(fonts[f].fsys, p)
+	if err != nil {
+		return internal.Wrap(`while opening font file "`+p+`"`, err)
+	}
+	defer fl.Close()
+
+	d, err := bmfont.Read(fl)
+	if err != nil {
+		return internal.Wrap(`while decoding font file "`+p+`"`, err)
+	}
+
+	pages := make([]image.Image, len(d.Pages))
+	for i, pg := range d.Pages {
+		if pg == "" {
+			continue // Page id not declared
+		}
+		pp := path.Join(path.Dir(p), pg)
+		pl, err := open(fonts[f].fsys, pp)
+		if err != nil {
+			return internal.Wrap(`while opening font page "`+pp+`"`, err)
+		}
+		pages[i], _, err = image.Decode(pl)
+		pl.Close()
+		if err != nil {
+			return internal.Wrap(`while decoding font page "`+pp+`"`, err)
+		}
+	}
+
+	gg := make([]fontGlyph, 0, len(d.Chars))
+	for _, c := range d.Chars {
+		if c.Page < 0 || c.Page >= len(pages) || pages[c.Page] == nil {
+			return errors.New("impossible to load font " + p + " (invalid page)")
+		}
+		gg = append(gg, fontGlyph{
+			rune:    c.ID,
+			image:   pages[c.Page],
+			rect:    image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height),
+			x:       c.XOffset,
+			y:       c.YOffset,
+			advance: c.XAdvance,
+		})
+	}
+
+	kk := make(map[[2]rune]int16, len(d.Kernings))
+	for _, k := range d.Kernings {
+		kk[[2]rune{k.First, k.Second}] = int16(k.Amount)
+	}
+
+	return f.build(gg, d.LineHeight, d.Base, kk, '?')
+}
+
+func (f FontID) loadBDF() error {
+	p := fontPaths[f]
+	fl, err := open(fonts[f].fsys, p)
+	if err != nil {
+		return internal.Wrap(`while opening font file "`+p+`"`, err)
+	}
+	defer fl.Close()
+
+	d, err := bdf.Read(fl)
+	if err != nil {
+		return internal.Wrap(`while decoding font file "`+p+`"`, err)
+	}
+
+	gg := make([]fontGlyph, 0, len(d.Glyphs))
+	for _, g := range d.Glyphs {
+		if g.Encoding < 0 {
+			continue
+		}
+		gg = append(gg, fontGlyph{
+			rune:    g.Encoding,
+			image:   g.Bitmap,
+			rect:    g.Bitmap.Bounds(),
+			x:       g.BBX.XOffset,
+			y:       d.Ascent - g.BBX.YOffset - g.BBX.Height,
+			advance: g.DWidth,
+		})
+	}
+
+	fb := '?'
+	if d.DefaultChar >= 0 {
+		fb = d.DefaultChar
+	}
+	return f.build(gg, d.Ascent+d.Descent, d.Ascent, nil, fb)
+}
+
+// build creates the pictures of the glyphs of a font. All glyphs are given the
+// same height, so that they are aligned when painted with the same y
+// coordinate.
+func (f FontID) build(gg []fontGlyph, lineHeight, base int, kerning map[[2]rune]int16, fallback rune) error {
+	if len(gg) == 0 {
+		return errors.New("impossible to load font " + fontPaths[f] + " (no glyphs)")
+	}
+	sort.SliceStable(gg, func(i, j int) bool { return gg[i].rune < gg[j].rune })
+
+	top, bottom := 0, lineHeight
+	for _, g := range gg {
+		if g.y < top {
+			top = g.y
+		}
+		if b := g.y + g.rect.Dy(); b > bottom {
+			bottom = b
+		}
+	}
+	h := bottom - top
+
+	fn := &fonts[f]
+	fn.height = int16(h)
+	fn.baseline = int16(base - top)
+	fn.basecolor = 1
+	fn.first = uint16(len(pictures.mapping))
+	fn.runes = make(map[rune]uint16, len(gg))
+	fn.offsets = make([]int16, len(gg))
+	fn.advances = make([]int16, len(gg))
+	fn.kerning = kerning
+
+	pal := stdcolor.Palette{stdcolor.Transparent, stdcolor.White}
+	for i, g := range gg {
+		m := image.NewPaletted(image.Rect(0, 0, g.rect.Dx(), h), pal)
+		for y := g.rect.Min.Y; y < g.rect.Max.Y; y++ {
+			for x := g.rect.Min.X; x < g.rect.Max.X; x++ {
+				if inked(g.image.At(x, y)) {
+					m.Pix[m.PixOffset(x-g.rect.Min.X, y-g.rect.Min.Y+g.y-top)] = 1
+				}
+			}
+		}
+		picture(m)
+		fn.runes[g.rune] = uint16(i)
+		fn.offsets[i] = int16(g.x)
+		fn.advances[i] = int16(g.advance)
+	}
+	// Without the fallback rune, the first glyph (lowest rune) is used
+	fn.fallback = 0
+	if g, ok := fn.runes[fallback]; ok {
+		fn.fallback = g
+	}
+
+	internal.Debug.Printf(
+		"Loaded font %s (%d glyphs, height %d)",
+		fontPaths[f],
+		len(gg),
+		fn.height,
+	)
+
+	return nil
+}
+
+// inked returns true if a pixel of a font page is part of a glyph, i.e. it is
+// opaque and not black.
+func inked(c stdcolor.Color) bool {
+	n := stdcolor.NRGBAModel.Convert(c).(stdcolor.NRGBA)
+	return n.A >= 0x80 && (n.R >= 0x80 || n.G >= 0x80 || n.B >= 0x80)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"bytes"
+	"image"
+	stdcolor "image/color"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestBitmapFonts(t *testing.T) {
+	fnt, err := os.ReadFile("../formats/bmfont/testdata/font.fnt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdf, err := os.ReadFile("../formats/bdf/testdata/font.bdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The page of the BMFont: an "A" (a 3x5 white box) on a transparent
+	// background, and a black "V" (which is not inked)
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 3; x++ {
+			m.Set(x, y, stdcolor.White)
+			m.Set(4+x, y, stdcolor.Black)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"fonts/tiny.fnt":   {Data: fnt},
+		"fonts/font_0.png": pngFile(t, m),
+		"tiny.bdf":         {Data: bdf},
+	}
+	bmf := FontFS(fsys, "fonts/tiny.fnt")
+	bdff := FontFS(fsys, "tiny.bdf")
+
+	defer headless(t, XY{12, 7})()
+
+	// Metrics
+
+	for _, c := range []struct {
+		name             string
+		font             FontID
+		height, baseline int16
+	}{
+		{"BMFont", bmf, 9, 6},
+		{"BDF", bdff, 8, 6},
+	} {
+		if c.font.Height() != c.height || fonts[c.font].baseline != c.baseline {
+			t.Errorf("%s: want height %d and baseline %d, got %d and %d",
+				c.name, c.height, c.baseline, c.font.Height(), fonts[c.font].baseline)
+		}
+		g := c.font.glyph('g')
+		if s := PictureID(g).Size(); s != (XY{3, c.height}) {
+			t.Errorf("%s: size of glyph 'g': got %v", c.name, s)
+		}
+		if c.font.offset(g) != -1 || c.font.advance(g) != 3 {
+			t.Errorf("%s: metrics of glyph 'g': got offset %d, advance %d",
+				c.name, c.font.offset(g), c.font.advance(g))
+		}
+	}
+
+	if bdff.glyph('z') != bdff.glyph('?') {
+		t.Errorf("BDF: missing runes should use the default char")
+	}
+	if bmf.glyph('z') != bmf.glyph('A') {
+		t.Errorf("BMFont: without '?', missing runes should use the first glyph")
+	}
+
+	a := pictures.image[bmf.glyph('A')]
+	if a.Pix[0] != 0 || a.Pix[a.Stride] != 1 || a.Pix[5*a.Stride+2] != 1 || a.Pix[6*a.Stride] != 0 {
+		t.Errorf("BMFont: wrong pixels for glyph 'A': %v", a.Pix)
+	}
+	if v := pictures.image[bmf.glyph('V')]; bytes.IndexByte(v.Pix, 1) >= 0 {
+		t.Errorf("BMFont: black pixels should not be inked")
+	}
+
+	// Kerning
+
+	c := Cursor{Font: bmf, Color: 1, Interline: 10}
+	if w := c.Measure("AV").X; w != 7 {
+		t.Errorf("width of \"AV\" with kerning: want 7, got %d", w)
+	}
+	if w := c.Measure("VA").X; w != 8 {
+		t.Errorf("width of \"VA\": want 8, got %d", w)
+	}
+	Clear(0)
+	c.Color = 3
+	c.Locate(0, XY{1, 6})
+	c.Print("AVA")
+	if c.Position.X != 12 {
+		t.Errorf("cursor after \"AVA\": want x 12, got %d", c.Position.X)
+	}
+	render()
+	want := "000000000000\n" + strings.Repeat("033300003330\n", 5) + "000000000000"
+	if got := dump(software.canvas.Pix, 12); got != want {
+		t.Errorf("text with kerning:\n%s", got)
+	}
+
+	c.Locate(0, XY{10, 10})
+	c.Print("AV")
+	if c.Position.X != 17 {
+		t.Errorf("cursor after \"AV\": want x 17, got %d", c.Position.X)
+	}
+	c.Print("A")
+	c.Position.X = 30
+	c.Print("V")
+	if c.Position.X != 34 {
+		t.Errorf("kerning should not apply after moving the cursor: got x %d", c.Position.X)
+	}
+	if err := render(); err != nil {
+		t.Error(err)
+	}
+
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBitmapFontPages(t *testing.T) {
+	defer headless(t, XY{1, 1})()
+
+	// Three page ids, but only page 2 is declared
+	desc := "common lineHeight=4 base=3 pages=3\npage id=2 file=\"page2.png\"\n" +
+		"char id=65 x=0 y=0 width=3 height=3 xadvance=4 page="
+	fsys := fstest.MapFS{
+		"page2.png":   pngFile(t, image.NewNRGBA(image.Rect(0, 0, 4, 4))),
+		"sparse.fnt":  {Data: []byte(desc + "2\n")},
+		"missing.fnt": {Data: []byte(desc + "1\n")},
+	}
+
+	// Only the named pages are opened
+	if err := FontFS(fsys, "sparse.fnt").loadBMFont(); err != nil {
+		t.Errorf("font with sparse pages: %v", err)
+	}
+	if err := FontFS(fsys, "missing.fnt").loadBMFont(); err == nil {
+		t.Errorf("char on an undeclared page accepted")
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"image"
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Camera displays a part of the world in a viewport, i.e. a rectangle of the
+// canvas. Once in use, all drawing commands take world coordinates: they are
+// translated according to the scrolling, and clipped to the viewport.
+//
+// Several cameras can be used during the same frame, e.g. for split-screen.
+type Camera struct {
+	Position XY // top-left corner of the viewport on the canvas
+	Size     XY // size of the viewport, or {0, 0} for the whole Resolution()
+	Scroll   XY // point of the world displayed at the top-left of the viewport
+
+	shake         int16
+	shakeStart    float64
+	shakeDuration float64
+}
+
+// view is the transformation applied to the commands queued after it.
+type view struct {
+	first  uint32          // index of the first command
+	offset XY              // translation, in canvas pixels
+	clip   image.Rectangle // in canvas pixels (i.e. including the margin)
+	filter bool            // draw in the filter instead of the canvas
+	target CanvasID
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Use applies the camera to all drawing commands queued after it, until the
+// end of the frame or the next call to Use or ResetCamera.
+//
+// The parallax factor scales the scrolling: use 1 for the layers of the level
+// itself, and smaller values for backgrounds (0 keeps them still). The time t
+// (in seconds, usually cozely.GameTime) is used for the screen shake.
+func (c *Camera) Use(parallax float64, t float64) {
+	o := c.Position.Minus(c.Offset(parallax)).Plus(c.shaking(t))
+
+	v := currentView()
+	fs, fm := v.target.frame()
+	p := c.Position.Plus(fm)
+	s := c.size()
+	r := image.Rect(int(p.X), int(p.Y), int(p.X+s.X), int(p.Y+s.Y))
+
+	setView(view{
+		offset: o,
+		clip:   r.Intersect(image.Rect(0, 0, int(fs.X), int(fs.Y))),
+		filter: v.filter,
+		target: v.target,
+	})
+}
+
+// ResetCamera cancels the effect of the last camera used: drawing commands
+// queued after it take canvas coordinates, and are not clipped.
+func ResetCamera() {
+	c := currentView()
+	v := canvasView(c.target)
+	v.filter = c.filter
+	setView(v)
+}
+
+// Offset returns the scrolling of the camera with a parallax factor.
+func (c *Camera) Offset(parallax float64) XY {
+	return XY{
+		int16(math.Floor(float64(c.Scroll.X)*parallax + 0.5)),
+		int16(math.Floor(float64(c.Scroll.Y)*parallax + 0.5)),
+	}
+}
+
+// World returns the world coordinates (for a parallax factor of 1) of a point
+// of the canvas, e.g. the mouse cursor. The shake is ignored.
+func (c *Camera) World(p XY) XY {
+	return p.Minus(c.Position).Plus(c.Scroll)
+}
+
+// Visible returns the rectangle of the world visible through the camera, for a
+// parallax factor of 1.
+func (c *Camera) Visible() (min, max XY) {
+	return c.Scroll, c.Scroll.Plus(c.size())
+}
+
+func (c *Camera) size() XY {
+	if c.Size.Null() {
+		if t := currentView().target; t != Screen {
+			return t.Size()
+		}
+		return Resolution()
+	}
+	return c.Size
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Shake starts shaking the camera, for a duration (in seconds) starting at time
+// t. The amplitude of the movement, in pixels, decreases linearly from
+// strength to zero. A new shake replaces the previous one.
+func (c *Camera) Shake(strength int16, duration float64, t float64) {
+	c.shake = strength
+	c.shakeStart = t
+	c.shakeDuration = duration
+}
+
+// shaking returns the offset of the shake at time t. The movement changes 30
+// times per second, and depends only on the time (not on the frame rate).
+func (c *Camera) shaking(t float64) XY {
+	if c.shake == 0 || c.shakeDuration <= 0 {
+		return XY{}
+	}
+	e := t - c.shakeStart
+	if e < 0 || e >= c.shakeDuration {
+		return XY{}
+	}
+	a := int(float64(c.shake)*(1-e/c.shakeDuration) + 0.5)
+	if a < 1 {
+		return XY{}
+	}
+	h := uint32(int64(t*30)) * 2654435761
+	return XY{
+		int16(int(h>>8)%(2*a+1) - a),
+		int16(int(h>>20)%(2*a+1) - a),
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// canvasView returns the default view of a target, without translation or
+// clipping.
+func canvasView(t CanvasID) view {
+	s, _ := t.frame()
+	return view{
+		offset: XY{},
+		clip:   image.Rect(0, 0, int(s.X), int(s.Y)),
+		target: t,
+	}
+}
+
+// currentView returns the view applied to the next command.
+func currentView() view {
+	if len(renderer.views) == 0 {
+		return canvasView(Screen)
+	}
+	return renderer.views[len(renderer.views)-1]
+}
+
+func setView(v view) {
+	v.first = uint32(len(renderer.commands))
+	l := len(renderer.views)
+	if l > 0 && renderer.views[l-1].first == v.first {
+		// No command queued with the previous view
+		renderer.views[l-1] = v
+		return
+	}
+	renderer.views = append(renderer.views, v)
+}
+
+// eachView calls f for each view used during the frame, with the index of the
+// first command after it.
+func eachView(f func(v view, end uint32)) {
+	v := canvasView(Screen)
+	for _, w := range renderer.views {
+		if w.first > v.first {
+			f(v, w.first)
+		}
+		v = w
+	}
+	if n := uint32(len(renderer.commands)); n > v.first {
+		f(v, n)
+	}
+}
+
+// visible returns the rectangle, in the coordinates taken by the drawing
+// commands, that is visible with the current view.
+func visible() (min, max XY) {
+	v := currentView()
+	_, m := v.target.frame()
+	o := v.offset.Plus(m)
+	min = XY{int16(v.clip.Min.X), int16(v.clip.Min.Y)}.Minus(o)
+	max = XY{int16(v.clip.Max.X), int16(v.clip.Max.Y)}.Minus(o)
+	return min, max
+}
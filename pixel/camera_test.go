@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import "testing"
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCamera(t *testing.T) {
+	defer headless(t, XY{8, 4})()
+
+	// Split screen
+
+	left := Camera{Position: XY{0, 0}, Size: XY{4, 4}}
+	right := Camera{Position: XY{4, 0}, Size: XY{4, 4}, Scroll: XY{2, 0}}
+
+	Clear(1)
+	left.Use(1, 0)
+	Box(2, 2, 0, 0, XY{2, 1}, XY{5, 2})
+	right.Use(1, 0)
+	Box(2, 2, 0, 0, XY{2, 1}, XY{5, 2})
+	if min, max := visible(); min != (XY{2, 0}) || max != (XY{6, 4}) {
+		t.Errorf("visible: want %v-%v, got %v-%v", XY{2, 0}, XY{6, 4}, min, max)
+	}
+	right.Use(0, 0)
+	Point(3, 0, XY{0, 3})
+	ResetCamera()
+	Point(4, 0, XY{0, 0})
+	if len(renderer.commands) != 4 {
+		t.Errorf("want 4 commands, got %d", len(renderer.commands))
+	}
+	render()
+
+	want := "41111111\n11222222\n11222222\n11113111"
+	got := ""
+	for y := 0; y < 4; y++ {
+		if y > 0 {
+			got += "\n"
+		}
+		for x := 0; x < 8; x++ {
+			got += string("0123456789"[software.canvas.Pix[x+y*software.canvas.Stride]])
+		}
+	}
+	if got != want {
+		t.Errorf("split screen:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+	if len(renderer.views) != 0 {
+		t.Errorf("cameras not reset after render")
+	}
+
+	// Coordinates
+
+	if p := right.World(XY{5, 1}); p != (XY{3, 1}) {
+		t.Errorf("World: want %v, got %v", XY{3, 1}, p)
+	}
+	if o := right.Offset(0.5); o != (XY{1, 0}) {
+		t.Errorf("Offset: want %v, got %v", XY{1, 0}, o)
+	}
+
+	// Shake
+
+	c := Camera{}
+	c.Shake(2, 1, 10)
+	moved := false
+	for tm := 10.0; tm < 11; tm += 1.0 / 60 {
+		s := c.shaking(tm)
+		if s.X < -2 || s.X > 2 || s.Y < -2 || s.Y > 2 {
+			t.Errorf("shake at %v out of range: %v", tm, s)
+		}
+		if s != c.shaking(tm) {
+			t.Errorf("shake at %v not deterministic", tm)
+		}
+		moved = moved || !s.Null()
+	}
+	if !moved {
+		t.Errorf("camera did not shake")
+	}
+	if s := c.shaking(11); !s.Null() {
+		t.Errorf("shake after its duration: %v", s)
+	}
+}
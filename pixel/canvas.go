@@ -18,15 +18,46 @@ import (
 var screen = struct {
 	resolution XY    // fixed resolution, or {0, 0} for fixed zoom
 	zoom       int16 // in window pixels
-
-	size   XY        // size of the canvas
-	margin XY        // for fixed resolution only, = size - resolution
-	border window.XY // leftover from division by pixel size
+	scaling    Scaling
+	letterbox  color.LRGBA
+
+	size    XY                     // size of the canvas
+	margin  XY                     // for fixed resolution only, = size - resolution
+	border  window.XY              // leftover from division by pixel size
+	display window.XY              // size of the canvas on the window
+	scale   struct{ X, Y float64 } // size of a canvas pixel, in window pixels
+	dirty   bool                   // resize needed before next frame
 }{
 	resolution: XY{},
 	zoom:       2,
+	letterbox:  color.LRGBA{0, 0, 0, 1},
+	scale:      struct{ X, Y float64 }{2, 2},
 }
 
+// Scaling is the policy used to display a canvas of fixed resolution on the
+// window.
+type Scaling uint8
+
+// Available scaling policies. All of them keep the pixels square, except
+// Stretch.
+const (
+	// Extend uses the largest integer zoom, and extends the canvas to cover the
+	// whole window: the resolution is centered, and the margins around it
+	// are drawable (this is the default).
+	Extend Scaling = iota
+	// Letterbox uses the largest integer zoom, but the canvas is exactly the
+	// size of the resolution; the rest of the window is filled with the
+	// letterbox color.
+	Letterbox
+	// Fit uses the largest zoom, integer or not, that keeps the whole
+	// resolution visible; the rest of the window is filled with the letterbox
+	// color.
+	Fit
+	// Stretch scales the canvas to cover the whole window, without preserving
+	// its aspect ratio.
+	Stretch
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // SetResolution defines a target resolution for the automatic resizing of
@@ -35,34 +66,51 @@ var screen = struct {
 // It guarantees that:
 // - the canvas will never be smaller than the target resolution,
 // - the target resolution will occupy as much screen as possible.
+//
+// It can be called while the framework is running: the change takes effect
+// at the start of the next rendered frame.
 func SetResolution(r XY) {
-	//TODO: allow runtime changes (defered to render?)
-	if internal.Running {
-		setErr(errors.New("Resolution must be called before starting the framework"))
+	if r.X < 1 || r.Y < 1 {
+		setErr(errors.New("pixel resolution: invalid size"))
 		return
 	}
 	screen.resolution = r
-	// if internal.Running {
-	// 	resize()
-	// }
+	screen.dirty = true
 }
 
-// SetZoom sets the pixel size used to display the canvas.
+// SetZoom sets the pixel size used to display the canvas, and cancels any
+// target resolution: the canvas covers the whole window.
+//
+// It can be called while the framework is running: the change takes effect
+// at the start of the next rendered frame.
 func SetZoom(z int16) {
-	//TODO: allow runtime changes (defered to render?)
-	if internal.Running {
-		setErr(errors.New("Resolution must be called before starting the framework"))
-		return
-	}
 	if z < 1 {
 		z = 1
 	}
 	screen.zoom = z
 	screen.resolution = XY{}
 	screen.margin = XY{}
-	// if internal.Running {
-	// 	resize()
-	// }
+	screen.dirty = true
+}
+
+// SetScaling changes the way a canvas of fixed resolution is displayed (see
+// SetResolution). It has no effect with a fixed zoom.
+//
+// It can be called while the framework is running: the change takes effect
+// at the start of the next rendered frame.
+func SetScaling(s Scaling) {
+	if s > Stretch {
+		setErr(errors.New("pixel scaling: unknown policy"))
+		return
+	}
+	screen.scaling = s
+	screen.dirty = true
+}
+
+// SetLetterbox changes the color of the parts of the window not covered by the
+// canvas.
+func SetLetterbox(c color.Color) {
+	screen.letterbox = color.LRGBAof(c)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -72,10 +120,25 @@ func init() {
 }
 
 func resize() {
+	screen.dirty = false
+
 	//TODO: use window.XY
 	win := window.XY{internal.Window.Width, internal.Window.Height}
 
-	if !screen.resolution.Null() {
+	var size XY
+	var sz window.XY // size of the canvas on the window
+
+	switch {
+	case screen.resolution.Null():
+		// Extend the screen to cover the window
+		size = XY(win.Slash(screen.zoom))
+		screen.margin = XY{}
+
+	case screen.scaling == Fit || screen.scaling == Stretch:
+		size = screen.resolution
+		screen.margin = XY{}
+
+	default:
 		// Find best fit for pixel size
 		p := win.SlashXY(window.XYof(screen.resolution))
 		if p.X < p.Y {
@@ -86,20 +149,50 @@ func resize() {
 		if screen.zoom < 1 {
 			screen.zoom = 1
 		}
+		size = screen.resolution
+		if screen.scaling == Extend {
+			size = XY(win.Slash(screen.zoom))
+		}
+		screen.margin = size.Minus(screen.resolution).Slash(2)
 	}
 
-	// Extend the screen to cover the window
-	screen.size = XY(win.Slash(screen.zoom))
-	adjustScreenTextures()
+	switch {
+	case screen.resolution.Null() || screen.scaling == Extend || screen.scaling == Letterbox:
+		screen.scale.X = float64(screen.zoom)
+		screen.scale.Y = float64(screen.zoom)
+		sz = window.XY(size.Times(screen.zoom))
 
-	// For fixed resolution, compute the margin and fix the size
-	if !screen.resolution.Null() {
-		screen.margin = screen.size.Minus(screen.resolution).Slash(2)
+	case screen.scaling == Fit:
+		k := float64(win.X) / float64(size.X)
+		if ky := float64(win.Y) / float64(size.Y); ky < k {
+			k = ky
+		}
+		screen.scale.X, screen.scale.Y = k, k
+		sz = window.XY{int16(k * float64(size.X)), int16(k * float64(size.Y))}
+		screen.zoom = int16(k)
+		if screen.zoom < 1 {
+			screen.zoom = 1
+		}
+
+	case screen.scaling == Stretch:
+		screen.scale.X = float64(win.X) / float64(size.X)
+		screen.scale.Y = float64(win.Y) / float64(size.Y)
+		sz = win
+		screen.zoom = int16(screen.scale.X)
+		if screen.scale.Y < screen.scale.X {
+			screen.zoom = int16(screen.scale.Y)
+		}
+		if screen.zoom < 1 {
+			screen.zoom = 1
+		}
 	}
 
+	screen.size = size
+	adjustScreenTextures()
+
 	// Compute outside border
-	sz := window.XY(screen.size.Times(screen.zoom))
 	screen.border = win.Minus(sz).Slash(2)
+	screen.display = sz
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -120,7 +213,8 @@ func Resolution() XY {
 	return screen.size
 }
 
-// Zoom returns the size of one canvas pixel, in *window* pixels.
+// Zoom returns the size of one canvas pixel, in *window* pixels (rounded down
+// for the Fit and Stretch scaling policies).
 func Zoom() int16 {
 	return screen.zoom
 }
@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/internal"
+	"github.com/cozely/cozely/window"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestScaling(t *testing.T) {
+	internal.Config.Headless = true
+	w, h := internal.Window.Width, internal.Window.Height
+	saved := screen
+	defer func() {
+		internal.Config.Headless = false
+		internal.Window.Width, internal.Window.Height = w, h
+		screen = saved
+		software.cleanup()
+	}()
+
+	internal.Window.Width, internal.Window.Height = 100, 70
+
+	for _, c := range []struct {
+		name    string
+		set     func()
+		size    XY
+		margin  XY
+		border  window.XY
+		display window.XY
+		zoom    int16
+	}{
+		{"Extend", func() { SetResolution(XY{30, 20}); SetScaling(Extend) },
+			XY{33, 23}, XY{1, 1}, window.XY{0, 0}, window.XY{99, 69}, 3},
+		{"Letterbox", func() { SetScaling(Letterbox) },
+			XY{30, 20}, XY{}, window.XY{5, 5}, window.XY{90, 60}, 3},
+		{"Fit", func() { SetScaling(Fit) },
+			XY{30, 20}, XY{}, window.XY{0, 2}, window.XY{100, 66}, 3},
+		{"Stretch", func() { SetScaling(Stretch) },
+			XY{30, 20}, XY{}, window.XY{0, 0}, window.XY{100, 70}, 3},
+		{"Zoom", func() { SetZoom(4) },
+			XY{25, 17}, XY{}, window.XY{0, 1}, window.XY{100, 68}, 4},
+	} {
+		c.set()
+		if !screen.dirty {
+			t.Errorf("%s: change not scheduled", c.name)
+		}
+		resize()
+		if screen.size != c.size || screen.margin != c.margin || screen.border != c.border ||
+			screen.display != c.display || Zoom() != c.zoom {
+			t.Errorf("%s: got size %v, margin %v, border %v, display %v, zoom %d",
+				c.name, screen.size, screen.margin, screen.border, screen.display, Zoom())
+		}
+		if software.canvas.Rect.Dx() != int(c.size.X) || software.canvas.Rect.Dy() != int(c.size.Y) {
+			t.Errorf("%s: canvas not resized", c.name)
+		}
+	}
+
+	SetResolution(XY{30, 20})
+	SetScaling(Letterbox)
+	resize()
+	if p := XYof(window.XY{50, 35}); p != (XY{15, 10}) {
+		t.Errorf("XYof: want %v, got %v", XY{15, 10}, p)
+	}
+	if p := (XY{15, 10}).WindowXY(); p != (window.XY{50, 35}) {
+		t.Errorf("WindowXY: want %v, got %v", window.XY{50, 35}, p)
+	}
+
+	SetScaling(Stretch)
+	resize()
+	if p := XYof(window.XY{99, 69}); p != (XY{29, 19}) {
+		t.Errorf("XYof with stretch: want %v, got %v", XY{29, 19}, p)
+	}
+}
@@ -4,6 +4,8 @@
 package pixel
 
 import (
+	"errors"
+
 	"github.com/cozely/cozely/color"
 )
 
@@ -17,6 +19,8 @@ const (
 	cmdLines      = 5
 	cmdTriangles  = 6
 	cmdBox        = 7
+	cmdTilemap    = 8
+	cmdSlice      = 9
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -26,6 +30,52 @@ func (p PictureID) Paint(layer int16, pos XY) {
 	renderer.command(cmdPicture, 4, 1, int16(p), layer, pos.X, pos.Y)
 }
 
+// An Orientation describes how a picture is flipped or rotated when painted.
+type Orientation uint8
+
+// Available orientations. Note that FlipX|FlipY is the same as Rotate180.
+const (
+	Upright          Orientation = 0
+	FlipX            Orientation = flipX
+	FlipY            Orientation = flipY
+	Rotate180        Orientation = flipX | flipY
+	FlipDiagonal     Orientation = transpose
+	Rotate90         Orientation = transpose | flipX // clockwise
+	Rotate270        Orientation = transpose | flipY // clockwise
+	FlipAntiDiagonal Orientation = transpose | flipX | flipY
+)
+
+// Orientation bits (the flips are done after the transposition).
+const (
+	flipX     = 0x01
+	flipY     = 0x02
+	transpose = 0x04
+)
+
+// PaintExt queues a GPU command to put a transformed picture on the canvas.
+// The picture is oriented, then magnified by an integer scale (between 1 and
+// 255), and its colors are changed with a remapping table (NoRemap keeps the
+// original colors). The position is the top-left corner of the result.
+func (p PictureID) PaintExt(layer int16, pos XY, o Orientation, scale int16, r RemapID) {
+	if scale < 1 || scale > 0xFF {
+		setErr(errors.New("pixel paint: invalid scale"))
+		return
+	}
+	renderer.command(cmdPictureExt, 4, 1,
+		int16(p), layer, pos.X, pos.Y,
+		int16(uint16(scale)<<8|uint16(o&0x07)),
+		int16(r))
+}
+
+// SizeExt returns the size of a picture painted with an orientation and scale.
+func (p PictureID) SizeExt(o Orientation, scale int16) XY {
+	s := p.Size()
+	if o&transpose != 0 {
+		s.X, s.Y = s.Y, s.X
+	}
+	return s.Times(scale)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // Point queues a GPU command to draw a point on the canvas.
@@ -5,6 +5,7 @@ package pixel
 
 import (
 	"fmt"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/cozely/cozely/color"
@@ -13,6 +14,9 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 
 // A Cursor is used to write text on the canvas.
+//
+// When Markup is true, the text can contain tags that change the style of the
+// cursor (see Write).
 type Cursor struct {
 	Color         color.Index
 	Font          FontID
@@ -21,6 +25,19 @@ type Cursor struct {
 	Interline     int16
 	Position      XY
 	Layer         int16
+	Markup        bool
+
+	base textStyle // style restored at the end of each Write
+	last lastRune  // last rune written, for kerning
+}
+
+// lastRune is the last rune written by a cursor, with its font and the
+// position of the cursor afterward. Kerning only applies if the next rune is
+// written with the same font, at the same position.
+type lastRune struct {
+	r    rune
+	font FontID
+	end  XY
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -40,6 +57,7 @@ func (a *Cursor) Locate(layer int16, p XY) {
 	a.Layer = layer
 	a.Position = XY{p.X, p.Y}
 	a.Margin = a.Position.X
+	a.last = lastRune{}
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -82,8 +100,30 @@ func (a *Cursor) Printf(format string, args ...interface{}) (n int, err error) {
 
 // Write asks the GPU to display p (interpreted as an UTF8 string) on the
 // canvas. This method implements the io.Writer interface.
+//
+// If the cursor Markup is true, the following tags are interpreted:
+//
+//	{c:8}         changes the color to index 8
+//	{c:Red}       changes the color to the index named "Red" in the palette
+//	{c}           restores the color of the cursor
+//	{f:1}         changes the font to FontID 1
+//	{f:fonts/x}   changes the font to the one declared with path "fonts/x"
+//	{f}           restores the font of the cursor
+//	{{            displays a single "{"
+//
+// Anything else is displayed as is. The changes only last until the end of the
+// call, so that each Print starts with the style of the cursor.
 func (a *Cursor) Write(p []byte) (n int, err error) {
 	n = len(p)
+	if a.Markup {
+		a.defaults()
+		a.base = textStyle{a.Color, a.Font}
+		a.each(string(p), func(r rune, _ int) {
+			a.WriteRune(r)
+		})
+		a.Color, a.Font = a.base.color, a.base.font
+		return n, nil
+	}
 	for len(p) > 0 {
 		r, s := utf8.DecodeRune(p)
 		a.WriteRune(r)
@@ -94,25 +134,196 @@ func (a *Cursor) Write(p []byte) (n int, err error) {
 
 // WriteRune asks the GPU to display a single rune on the canvas.
 func (a *Cursor) WriteRune(r rune) {
-	if a.Color == 0 && a.Font == 0 && a.Interline == 0 {
-		a.Color = 7
-		a.Interline = int16(float32(a.Font.Height()) * 1.25)
-		if a.Position.X == 0 && a.Position.Y == 0 {
-			a.Position.X = 4
-			a.Position.Y = a.Interline
-		}
-	}
+	a.defaults()
 	if r == '\n' {
 		a.Position.Y += a.Interline
 		a.Position.X = a.Margin
 		return
 	}
 
+	if a.last.font == a.Font && a.last.end == a.Position {
+		a.Position.X += a.Font.kern(a.last.r, r)
+	}
 	g := a.Font.glyph(r)
 	renderer.command(cmdText, 4, 1,
 		int16(a.Color-fonts[a.Font].basecolor),
 		a.Layer,
 		a.Position.Y-fonts[a.Font].baseline,
-		int16(g), a.Position.X)
-	a.Position.X += pictures.mapping[g].w + a.LetterSpacing
+		int16(g), a.Position.X+a.Font.offset(g))
+	a.Position.X += a.Font.advance(g) + a.LetterSpacing
+	a.last = lastRune{r, a.Font, a.Position}
+}
+
+// defaults gives a sensible style to an uninitialized cursor.
+func (a *Cursor) defaults() {
+	if a.Color == 0 && a.Font == 0 && a.Interline == 0 {
+		a.Color = 7
+		a.Interline = int16(float32(a.Font.Height()) * 1.25)
+		if a.Position.X == 0 && a.Position.Y == 0 {
+			a.Position.X = 4
+			a.Position.Y = a.Interline
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// An Alignment specifies how lines of text are positioned inside a box.
+type Alignment uint8
+
+// Available alignments.
+const (
+	AlignLeft Alignment = iota
+	AlignCenter
+	AlignRight
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Measure returns the size that a text would occupy on the canvas, if displayed
+// with the cursor font, letter spacing and interline: the width of its longest
+// line, and the height from the top of the first line to the bottom of the
+// last one.
+//
+// Note that the size of the glyphs is only known once the framework is running.
+func (a *Cursor) Measure(s string) XY {
+	c := *a
+	c.base = textStyle{c.Color, c.Font}
+	lines := strings.Split(s, "\n")
+	w := int16(0)
+	for _, l := range lines {
+		lw := c.width(l)
+		if lw > w {
+			w = lw
+		}
+		c.skip(l)
+	}
+	h := a.Font.Height() + int16(len(lines)-1)*a.interline()
+	return XY{w, h}
+}
+
+// width returns the width of a single line of text (without the spacing after
+// the last letter).
+func (a *Cursor) width(s string) int16 {
+	c := *a
+	w, n := int16(0), int16(0)
+	p := lastRune{}
+	c.each(s, func(r rune, _ int) {
+		if n > 0 && p.font == c.Font {
+			w += c.Font.kern(p.r, r)
+		}
+		w += c.Font.advance(c.Font.glyph(r))
+		p = lastRune{r: r, font: c.Font}
+		n++
+	})
+	if n > 1 {
+		w += (n - 1) * a.LetterSpacing
+	}
+	return w
+}
+
+// fit returns the length (in bytes) of the longest prefix of a line of text
+// that is no wider than width. The prefix always contains at least one rune.
+func (a *Cursor) fit(s string, width int16) int {
+	c := *a
+	w, n, end := int16(0), 0, 0
+	full := false
+	p := lastRune{}
+	c.each(s, func(r rune, e int) {
+		if full {
+			return
+		}
+		gw := c.Font.advance(c.Font.glyph(r))
+		if n > 0 {
+			gw += c.LetterSpacing
+			if p.font == c.Font {
+				gw += c.Font.kern(p.r, r)
+			}
+			if w+gw > width {
+				full = true
+				return
+			}
+		}
+		w += gw
+		p = lastRune{r: r, font: c.Font}
+		n++
+		end = e
+	})
+	return end
+}
+
+// interline returns the interline used by the cursor, even if it hasn't been
+// initialized yet.
+func (a *Cursor) interline() int16 {
+	if a.Color == 0 && a.Font == 0 && a.Interline == 0 {
+		return int16(float32(a.Font.Height()) * 1.25)
+	}
+	return a.Interline
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Wrap splits a text into lines that are no wider than width. Lines are broken
+// at spaces when possible, and inside words that are too long to fit (a single
+// character wider than the box still gets its own line). Newlines already
+// present in the text are preserved.
+//
+// If the cursor Markup is true, the tags are kept in the lines, and their
+// effect is carried from one line to the next.
+func (a *Cursor) Wrap(width int16, s string) []string {
+	c := *a
+	c.base = textStyle{c.Color, c.Font}
+	lines := []string{}
+	add := func(l string) {
+		lines = append(lines, l)
+		c.skip(l)
+	}
+	for _, p := range strings.Split(s, "\n") {
+		line := ""
+		for _, w := range c.fields(p) {
+			switch {
+			case line == "":
+				line = w
+			case c.width(line+" "+w) <= width:
+				line += " " + w
+			default:
+				add(line)
+				line = w
+			}
+			for c.width(line) > width {
+				// Break the word
+				n := c.fit(line, width)
+				if n >= len(line) {
+					break
+				}
+				add(line[:n])
+				line = line[n:]
+			}
+		}
+		add(line)
+	}
+	return lines
+}
+
+// PrintBox displays a text inside a box, starting at the cursor margin and
+// position. The text is wrapped to the width of the box, and each line is
+// aligned inside it. Afterward, the cursor is at the start of the line
+// following the text.
+func (a *Cursor) PrintBox(width int16, align Alignment, s string) {
+	a.defaults()
+	a.base = textStyle{a.Color, a.Font}
+	for _, l := range a.Wrap(width, s) {
+		a.Position.X = a.Margin
+		switch align {
+		case AlignCenter:
+			a.Position.X += (width - a.width(l)) / 2
+		case AlignRight:
+			a.Position.X += width - a.width(l)
+		}
+		a.each(l, func(r rune, _ int) {
+			a.WriteRune(r)
+		})
+		a.WriteRune('\n')
+	}
+	a.Color, a.Font = a.base.color, a.base.font
 }
@@ -0,0 +1,135 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testFont declares a font with glyphs of known sizes: 'a' is 3 pixels wide,
+// 'b' is 5 pixels wide, and space is 2 pixels wide. It returns a function to
+// remove it.
+func testFont() (FontID, func()) {
+	m, f := len(pictures.mapping), len(fonts)
+	pictures.mapping = append(pictures.mapping,
+		mapping{w: 3, h: 8}, mapping{w: 5, h: 8}, mapping{w: 2, h: 8},
+	)
+	fonts = append(fonts, font{
+		height:   8,
+		first:    uint16(m),
+		runes:    map[rune]uint16{'a': 0, 'b': 1, ' ': 2},
+		fallback: 1,
+	})
+	return FontID(f), func() {
+		pictures.mapping = pictures.mapping[:m]
+		fonts = fonts[:f]
+	}
+}
+
+func TestCursorMeasure(t *testing.T) {
+	f, done := testFont()
+	defer done()
+
+	c := Cursor{Font: f, Interline: 10, LetterSpacing: 1}
+	for _, m := range []struct {
+		s    string
+		size XY
+	}{
+		{"", XY{0, 8}},
+		{"a", XY{3, 8}},
+		{"ab", XY{9, 8}},
+		{"a b", XY{3 + 2 + 5 + 2, 8}},
+		{"ab\naaa", XY{11, 18}},
+		{"\n\n", XY{0, 28}},
+	} {
+		if s := c.Measure(m.s); s != m.size {
+			t.Errorf("Measure(%q): want %v, got %v", m.s, m.size, s)
+		}
+	}
+}
+
+func TestCursorMeasureUnloaded(t *testing.T) {
+	// A strip font declared but not yet loaded has no glyph pictures
+	f := len(fonts)
+	fonts = append(fonts, font{
+		first: uint16(len(pictures.mapping)),
+		runes: map[rune]uint16{'a': 0},
+	})
+	defer func() { fonts = fonts[:f] }()
+
+	c := Cursor{Font: FontID(f), LetterSpacing: 1}
+	if s := c.Measure("aa"); s.X != 1 {
+		t.Errorf("Measure of unloaded font: want width 1, got %v", s)
+	}
+	if l := c.Wrap(10, "a a"); len(l) != 1 {
+		t.Errorf("Wrap of unloaded font: want 1 line, got %q", l)
+	}
+}
+
+func TestCursorWrap(t *testing.T) {
+	f, done := testFont()
+	defer done()
+
+	c := Cursor{Font: f}
+	for _, w := range []struct {
+		s     string
+		width int16
+		lines []string
+	}{
+		{"aa aa aa", 14, []string{"aa aa", "aa"}},
+		{"aa aa aa", 22, []string{"aa aa aa"}},
+		{"aa  aa\n\nb", 100, []string{"aa aa", "", "b"}},
+		{"aaaaa b", 9, []string{"aaa", "aa", "b"}},
+		{"aaaaa b", 13, []string{"aaaa", "a b"}},
+		{"bb", 1, []string{"b", "b"}},
+	} {
+		if l := c.Wrap(w.width, w.s); !reflect.DeepEqual(l, w.lines) {
+			t.Errorf("Wrap(%d, %q): want %q, got %q", w.width, w.s, w.lines, l)
+		}
+	}
+}
+
+func TestCursorMarkup(t *testing.T) {
+	f, done := testFont()
+	defer done()
+	g, done2 := testFont()
+	defer done2()
+	pictures.mapping[fonts[g].first] = mapping{w: 7, h: 8} // 'a' in font g
+
+	c := Cursor{Font: f, Markup: true}
+	for _, m := range []struct {
+		s     string
+		width int16
+	}{
+		{"{c:3}a", 3},
+		{"{f:" + strconv.Itoa(int(g)) + "}a{f}a", 10},
+		{"{{", 5},
+		{"{x}", 15},
+		{"{c:3", 20},
+	} {
+		if w := c.Measure(m.s).X; w != m.width {
+			t.Errorf("Measure(%q): want width %d, got %d", m.s, m.width, w)
+		}
+	}
+
+	c.Font = f
+	ff := c.fields("a{c:1 } b {c:2}a")
+	if want := []string{"a{c:1", "}", "b", "{c:2}a"}; !reflect.DeepEqual(ff, want) {
+		t.Errorf("fields: want %q, got %q", want, ff)
+	}
+
+	l := c.Wrap(8, "{c:2}aaa")
+	if want := []string{"{c:2}aa", "a"}; !reflect.DeepEqual(l, want) {
+		t.Errorf("Wrap: want %q, got %q", want, l)
+	}
+
+	c.Markup = false
+	if w := c.Measure("{c:3}").X; w != 5*5 {
+		t.Errorf("Measure without markup: want width %d, got %d", 5*5, w)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"math"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Palette effects are applied once per frame, just before rendering, using
+// the current value of cozely.GameTime. They only change the colors displayed:
+// the palette itself (as returned by LRGBAof) is left untouched, except when a
+// transition ends.
+
+var effects struct {
+	cycles []cycle
+
+	// Transition
+	fading   bool
+	target   [256]color.LRGBA
+	byName   map[string]color.Index
+	count    int
+	start    float64
+	duration float64
+}
+
+type cycle struct {
+	first, last color.Index
+	step        float64 // in seconds, negative for reverse cycling
+	start       float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CycleColors starts rotating the colors of a range of indices (both ends
+// included), shifting them by one index every step seconds. Colors move toward
+// higher indices, or lower ones if step is negative. This is the classic way
+// to animate water, fire or conveyor belts without redrawing anything.
+//
+// Several ranges can cycle at the same time, but they should not overlap.
+func CycleColors(first, last color.Index, step float64) {
+	if last <= first || step == 0 {
+		return
+	}
+	effects.cycles = append(effects.cycles, cycle{
+		first: first,
+		last:  last,
+		step:  step,
+		start: internal.GameTime,
+	})
+}
+
+// StopCycling stops the rotation of all color ranges.
+func StopCycling() {
+	effects.cycles = effects.cycles[:0]
+	palette.dirty = true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Transition changes the palette progressively, interpolating each color
+// between its current value and the one in p, over a duration in seconds
+// (starting now). For example, a fade to black is a transition to a palette
+// where all colors are black.
+//
+// At the end of the transition, the new palette replaces the old one, as with
+// SetPalette.
+func Transition(p color.Palette, duration float64) {
+	if duration <= 0 {
+		SetPalette(p)
+		effects.fading = false
+		return
+	}
+	effects.target = palette.colors
+	for i := 1; i < len(effects.target); i++ {
+		if i-1 < len(p.Colors) {
+			effects.target[i] = p.Colors[i-1]
+		} else {
+			effects.target[i] = debugColor
+		}
+	}
+	effects.byName = p.ByName
+	effects.count = paletteCount(p)
+	effects.start = internal.GameTime
+	effects.duration = duration
+	effects.fading = true
+}
+
+// Transitioning returns true if a palette transition is in progress.
+func Transitioning() bool {
+	return effects.fading
+}
+
+// BlendPalettes changes the palette to an interpolation between a and b: t
+// equal to 0 gives a, and 1 gives b. It is meant for gradual changes
+// controlled by the game, e.g. a day and night cycle.
+func BlendPalettes(a, b color.Palette, t float32) {
+	SetPalette(a)
+	for i := range b.Colors {
+		if i+1 >= len(palette.colors) {
+			break
+		}
+		palette.colors[i+1] = lerp(palette.colors[i+1], b.Colors[i], t)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// updatePalette computes the displayed colors, and marks the palette as dirty
+// if they need to be uploaded.
+func updatePalette() {
+	if !effects.fading && len(effects.cycles) == 0 {
+		if palette.dirty {
+			palette.shown = palette.colors
+		}
+		return
+	}
+
+	t := internal.GameTime
+
+	base := palette.colors
+	if effects.fading {
+		k := float32((t - effects.start) / effects.duration)
+		if k >= 1 {
+			palette.colors = effects.target
+			palette.byName = effects.byName
+			palette.count = effects.count
+			effects.fading = false
+			k = 1
+		}
+		for i := range base {
+			base[i] = lerp(base[i], effects.target[i], k)
+		}
+	}
+
+	palette.shown = base
+	for _, c := range effects.cycles {
+		n := int(c.last) - int(c.first) + 1
+		k := int(math.Floor((t - c.start) / math.Abs(c.step)))
+		if c.step < 0 {
+			k = -k
+		}
+		for i := 0; i < n; i++ {
+			j := ((i-k)%n + n) % n
+			palette.shown[int(c.first)+i] = base[int(c.first)+j]
+		}
+	}
+
+	palette.dirty = true
+}
+
+// lerp interpolates linearly between two colors.
+func lerp(a color.LRGBA, b color.Color, t float32) color.LRGBA {
+	c := color.LRGBAof(b)
+	return color.LRGBA{
+		R: a.R + t*(c.R-a.R),
+		G: a.G + t*(c.G-a.G),
+		B: a.B + t*(c.B-a.B),
+		A: a.A + t*(c.A-a.A),
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestPaletteEffects(t *testing.T) {
+	defer func() {
+		internal.GameTime = 0
+		StopCycling()
+		effects.fading = false
+		SetPalette(DefaultPalette)
+	}()
+
+	gray := func(v float32) color.LRGBA { return color.LRGBA{v, v, v, 1} }
+	p := color.Palette{
+		ByName: map[string]color.Index{"a": 1},
+		Colors: []color.LRGBA{gray(0.1), gray(0.2), gray(0.3), gray(0.4)},
+	}
+	black := color.Palette{
+		ByName: map[string]color.Index{"b": 1},
+		Colors: []color.LRGBA{gray(0), gray(0), gray(0), gray(0)},
+	}
+
+	// Cycling
+
+	internal.GameTime = 10
+	SetPalette(p)
+	CycleColors(1, 3, 0.5)
+
+	for _, c := range []struct {
+		t    float64
+		want [4]float32
+	}{
+		{10.2, [4]float32{0.1, 0.2, 0.3, 0.4}},
+		{10.7, [4]float32{0.3, 0.1, 0.2, 0.4}},
+		{11.2, [4]float32{0.2, 0.3, 0.1, 0.4}},
+		{11.6, [4]float32{0.1, 0.2, 0.3, 0.4}},
+	} {
+		internal.GameTime = c.t
+		updatePalette()
+		for i, w := range c.want {
+			if palette.shown[i+1] != gray(w) {
+				t.Errorf("cycling at %v: color %d is %v, want %v", c.t, i+1, palette.shown[i+1].R, w)
+			}
+		}
+	}
+	if LRGBAof(1) != gray(0.1) {
+		t.Errorf("cycling changed the palette")
+	}
+	StopCycling()
+	updatePalette()
+	if palette.shown[1] != gray(0.1) || palette.shown[3] != gray(0.3) {
+		t.Errorf("colors not restored after cycling")
+	}
+
+	// Transition
+
+	internal.GameTime = 20
+	Transition(black, 2)
+	internal.GameTime = 21
+	updatePalette()
+	if !Transitioning() || palette.shown[2] != gray(0.1) || LRGBAof(2) != gray(0.2) {
+		t.Errorf("transition at midpoint: shown %v, palette %v", palette.shown[2], LRGBAof(2))
+	}
+	internal.GameTime = 22.5
+	updatePalette()
+	if Transitioning() || palette.shown[2] != gray(0) || LRGBAof(2) != gray(0) || palette.byName["b"] != 1 {
+		t.Errorf("transition not completed: shown %v, palette %v", palette.shown[2], LRGBAof(2))
+	}
+
+	// Blending
+
+	BlendPalettes(p, black, 0.25)
+	if c := LRGBAof(4); c.R < 0.2999 || c.R > 0.3001 {
+		t.Errorf("blend: want 0.3, got %v", c.R)
+	}
+}
@@ -10,5 +10,9 @@ fixed, resolution (usually lower than the screen resolution).
 It does not provide any anti-aliasing, nor alpha-transparency, since the goal is
 to offer an easy way to work within the stricter definition of pixel art (i.e.
 limited color palette, no mixed-resolution or "mixels", and so on).
+
+When the framework is headless (see cozely.Headless), the same drawing commands
+are executed by a software renderer, with identical results; this allows to
+test the rendering of a game on machines without GPU.
 */
 package pixel
@@ -0,0 +1,88 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// The filter is a second layer of indices, covering the canvas, that changes
+// the colors of the canvas when it is displayed: each pixel of the filter is
+// the ID of a remapping table (see Remap), applied to the corresponding pixel
+// of the canvas. The filter is reset to NoRemap by Clear.
+//
+// Since the remapping stays within the palette, this can be used for pixel-art
+// lighting, shadows or fog.
+
+// UseFilter redirects all drawing commands queued after it to the filter,
+// until the end of the frame or the next call to UseCanvas. The color indices
+// of these commands (including those of pictures) are interpreted as RemapIDs.
+//
+// The filter shares the depth information of the canvas: filter commands are
+// hidden by what has been drawn on the canvas on higher layers, but do not
+// hide anything themselves.
+func UseFilter() {
+	v := currentView()
+	if v.target != Screen {
+		setErr(errors.New("pixel filter: not available on off-screen canvases"))
+		return
+	}
+	v.filter = true
+	setView(v)
+}
+
+// UseCanvas redirects all drawing commands queued after it to the canvas
+// (which is the default at the start of each frame).
+func UseCanvas() {
+	v := currentView()
+	v.filter = false
+	setView(v)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Shade declares a new remapping table that moves each color along its ramp,
+// by a number of steps (negative to go backward). A ramp is a list of color
+// indices, e.g. from the darkest to the lightest shade of a color; colors
+// moved past the end of their ramp are clamped. Colors that are not in any
+// ramp are kept.
+//
+// For example, with ramps from dark to light, Shade(-1, ramps...) is a shadow
+// and Shade(1, ramps...) a light.
+func Shade(steps int, ramps ...[]color.Index) RemapID {
+	t := identityRemap()
+	for _, r := range ramps {
+		if len(r) == 0 {
+			setErr(errors.New("pixel shade declaration: empty ramp"))
+			return NoRemap
+		}
+		for i, c := range r {
+			j := i + steps
+			if j < 0 {
+				j = 0
+			}
+			if j >= len(r) {
+				j = len(r) - 1
+			}
+			t[c] = r[j]
+		}
+	}
+	return newRemap(t)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// applyFilter remaps the colors of the canvas pixels, like the blitting
+// shader.
+func applyFilter(canvas, filter []uint8) {
+	for i, f := range filter {
+		if f != 0 && int(f) < len(remaps.tables) {
+			canvas[i] = uint8(remaps.tables[f][canvas[i]])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestFilter(t *testing.T) {
+	defer headless(t, XY{4, 2})()
+
+	shadow := Shade(-1, []color.Index{1, 2, 3}, []color.Index{6, 7})
+	if shadow.Color(1) != 1 || shadow.Color(2) != 1 || shadow.Color(3) != 2 || shadow.Color(7) != 6 || shadow.Color(4) != 4 {
+		t.Errorf("unexpected shade table")
+	}
+
+	Clear(1)
+	Box(2, 2, 0, 0, XY{0, 0}, XY{3, 1})
+	Point(5, 5, XY{3, 0})
+	UseFilter()
+	Box(color.Index(shadow), color.Index(shadow), 3, 0, XY{0, 0}, XY{3, 1})
+	UseCanvas()
+	Point(4, 1, XY{0, 1})
+	render()
+
+	want := "2225\n4222"
+	if got := dump(software.canvas.Pix, 4); got != want {
+		t.Errorf("canvas:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+	want = "1110\n1111"
+	if got := dump(software.filter, 4); got != want {
+		t.Errorf("filter:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+	want = "1115\n4111"
+	if got := dump(Snapshot().Pix, 4); got != want {
+		t.Errorf("snapshot:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+
+	Clear(1)
+	render()
+	if got := dump(software.filter, 4); got != "0000\n0000" {
+		t.Errorf("filter not cleared:\n%s", got)
+	}
+}
+
+// dump returns a textual representation of small color indices.
+func dump(pix []uint8, stride int) string {
+	s := ""
+	for i, c := range pix {
+		if i > 0 && i%stride == 0 {
+			s += "\n"
+		}
+		s += string("0123456789ABCDEF"[c&0xF])
+	}
+	return s
+}
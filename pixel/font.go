@@ -4,11 +4,15 @@
 package pixel
 
 import (
+	"encoding/json"
 	"errors"
 	"image"
 	_ "image/png" // Activate PNG support
-	"os"
-	"path/filepath"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/cozely/cozely/color"
 	"github.com/cozely/cozely/internal"
@@ -36,13 +40,99 @@ type font struct {
 	height    int16
 	baseline  int16
 	basecolor color.Index
-	first     uint16 // index of the first glyph
+	first     uint16          // index of the first glyph
+	runes     map[rune]uint16 // glyph of each rune (nil for ASCII fonts)
+	fallback  uint16          // glyph used for missing runes
+	fsys      fs.FS           // file system containing the font (if not nil)
+	image     *image.Paletted // image of the glyphs (for fonts not in a file)
+	offsets   []int16         // horizontal offset of each glyph (nil for strip fonts)
+	advances  []int16         // advance width of each glyph (nil for strip fonts)
+	kerning   map[[2]rune]int16
+}
+
+// fontDescription is the content of the (optional) JSON file accompanying a
+// font image.
+type fontDescription struct {
+	// Strips is the number of glyph strips stacked vertically in the image.
+	Strips int
+	// Runes lists the runes corresponding to the glyphs, in order. Each entry
+	// is either a range ("a-z", with exactly one character on each side of the
+	// dash), or a sequence of individual characters ("àéèù").
+	Runes []string
+	// Fallback is the character displayed for runes missing from the font.
+	Fallback string
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 // Font declares a new font and returns its ID.
+//
+// The font is loaded from a PNG file made of one or more horizontal strips of
+// glyphs; below each strip, a marker row indicates the width of the glyphs. By
+// default, a font contains a single strip of the 96 glyphs from ' ' to 0x7F
+// (the latter being used for missing runes). Other characters can be described
+// in a JSON file with the same name, for example:
+//
+//	{
+//	  "Strips": 2,
+//	  "Runes": [" -~", "\u007F", "\u00A0-\u00FF", "А-я"],
+//	  "Fallback": "?"
+//	}
+//
+// The glyphs of all strips are then associated, in order, with the runes of
+// the list, which must have exactly one rune per glyph.
+//
+// If the path ends with ".fnt" or ".bdf", the font is instead loaded from a
+// BMFont description (text or binary, with its page images in the same
+// directory) or from a BDF file. Their glyphs can be offset from the cursor,
+// have their own advance width, and the kerning pairs of BMFont are applied by
+// Cursor. The opaque, non-black pixels of the pages are displayed with the
+// cursor color.
 func Font(path string) FontID {
+	return newFont(font{}, path)
+}
+
+// FontFS declares a new font loaded from the file "<path>.png" (and the
+// optional "<path>.json"), or from a BMFont or BDF file, inside fsys (for
+// example an embed.FS). It is otherwise identical to Font.
+func FontFS(fsys fs.FS, path string) FontID {
+	if fsys == nil {
+		setErr(errors.New("pixel font declaration: nil file system"))
+		return noFont
+	}
+	return newFont(font{fsys: fsys}, path)
+}
+
+// FontImage declares a new font from an image, which must contain a single
+// strip of the 96 glyphs from ' ' to 0x7F (with the marker row described in
+// Font). The name is only used to refer to the font in messages and markup.
+func FontImage(name string, img *image.Paletted) FontID {
+	if img == nil {
+		setErr(errors.New("pixel font declaration: nil image"))
+		return noFont
+	}
+	return newFont(font{image: img}, name)
+}
+
+// FontReader declares a new font from the content of r, which is decoded
+// immediately. It is otherwise identical to FontImage.
+func FontReader(name string, r io.Reader) FontID {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		setErr(internal.Wrap("pixel font declaration: decoding image", err))
+		return noFont
+	}
+
+	m, ok := img.(*image.Paletted)
+	if !ok {
+		setErr(errors.New("pixel font declaration: color model not supported"))
+		return noFont
+	}
+
+	return newFont(font{image: m}, name)
+}
+
+func newFont(f font, path string) FontID {
 	if internal.Running {
 		setErr(errors.New("pixel font declaration: declarations must happen before starting the framework"))
 		return noFont
@@ -53,7 +143,7 @@ func Font(path string) FontID {
 		return noFont
 	}
 
-	fonts = append(fonts, font{})
+	fonts = append(fonts, f)
 	fontPaths = append(fontPaths, path)
 	return FontID(len(fonts) - 1)
 }
@@ -61,16 +151,51 @@ func Font(path string) FontID {
 ////////////////////////////////////////////////////////////////////////////////
 
 func (f FontID) glyph(r rune) uint16 {
-	//TODO: add support for non-ascii runes
-	switch {
-	case r < ' ':
-		r = 0x7F - ' '
-	case r <= 0x7F:
-		r = r - ' '
-	default:
-		r = 0x7F - ' '
+	if fonts[f].runes == nil {
+		switch {
+		case r < ' ':
+			r = 0x7F - ' '
+		case r <= 0x7F:
+			r = r - ' '
+		default:
+			r = 0x7F - ' '
+		}
+		return fonts[f].first + uint16(r)
+	}
+
+	g, ok := fonts[f].runes[r]
+	if !ok {
+		g = fonts[f].fallback
 	}
-	return fonts[f].first + uint16(r)
+	return fonts[f].first + g
+}
+
+// parseRunes returns the list of runes described by a font description.
+func parseRunes(desc []string) ([]rune, error) {
+	rr := []rune{}
+	for _, d := range desc {
+		s := []rune(d)
+		if len(s) == 3 && s[1] == '-' {
+			if s[2] < s[0] {
+				return nil, errors.New(`invalid rune range "` + d + `"`)
+			}
+			for r := s[0]; r <= s[2]; r++ {
+				rr = append(rr, r)
+			}
+			continue
+		}
+		rr = append(rr, s...)
+	}
+	return rr, nil
+}
+
+// Has returns true if the font contains a glyph for a rune.
+func (f FontID) Has(r rune) bool {
+	if fonts[f].runes == nil {
+		return r >= ' ' && r < 0x7F
+	}
+	_, ok := fonts[f].runes[r]
+	return ok
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -81,23 +206,59 @@ func (f FontID) Height() int16 {
 	return fonts[f].height
 }
 
+// Baseline returns the distance between the top of the glyph images and the
+// baseline of the font (i.e. the position of the cursor).
+func (f FontID) Baseline() int16 {
+	return fonts[f].baseline
+}
+
+// advance returns the distance between the position of a glyph and the next
+// one (without letter spacing).
+func (f FontID) advance(g uint16) int16 {
+	if fonts[f].advances == nil {
+		if int(g) >= len(pictures.mapping) {
+			return 0 // Not loaded yet
+		}
+		return pictures.mapping[g].w
+	}
+	return fonts[f].advances[g-fonts[f].first]
+}
+
+// offset returns the horizontal distance between the cursor position and the
+// left side of a glyph.
+func (f FontID) offset(g uint16) int16 {
+	if fonts[f].offsets == nil {
+		return 0
+	}
+	return fonts[f].offsets[g-fonts[f].first]
+}
+
+// kern returns the adjustment of the distance between two runes.
+func (f FontID) kern(a, b rune) int16 {
+	return fonts[f].kerning[[2]rune{a, b}]
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 func (f FontID) load(frects *[]uint32) error {
+	switch {
+	case f != 0 && fonts[f].image == nil && strings.HasSuffix(fontPaths[f], ".fnt"):
+		return f.loadBMFont()
+	case f != 0 && fonts[f].image == nil && strings.HasSuffix(fontPaths[f], ".bdf"):
+		return f.loadBDF()
+	}
+
 	//TODO: support other image formats?
 	var p *image.Paletted
 
-	if f == 0 {
+	switch {
+	case f == 0:
 		p = &monozela10
-	} else {
-		n := fontPaths[f]
-		path := filepath.FromSlash(internal.Path + n + ".png")
-		path, err := filepath.EvalSymlinks(path)
-		if err != nil {
-			return internal.Wrap("in path while loading font", err)
-		}
-
-		fl, err := os.Open(path)
+	case fonts[f].image != nil:
+		p = fonts[f].image
+	default:
+		path := fontPaths[f] + ".png"
+		fl, err := open(fonts[f].fsys, path)
 		if err != nil {
 			return internal.Wrap(`while opening font file "`+path+`"`, err)
 		}
@@ -119,13 +280,22 @@ func (f FontID) load(frects *[]uint32) error {
 		}
 	}
 
-	h := p.Bounds().Dy() - 1
+	desc, err := f.description()
+	if err != nil {
+		return err
+	}
+	if desc.Strips < 1 || p.Bounds().Dy()%desc.Strips != 0 {
+		return errors.New("impossible to load font " + fontPaths[f] + " (invalid number of strips)")
+	}
+
+	sh := p.Bounds().Dy() / desc.Strips
+	h := sh - 1
 	fonts[f].height = int16(h)
 	g := uint16(len(pictures.mapping))
 	fonts[f].first = g
 	maxw := 0
 
-	for y := 0; y < p.Bounds().Dy(); y++ {
+	for y := 0; y < sh; y++ {
 		if p.Pix[0+y*p.Stride] != 0 {
 			fonts[f].baseline = int16(y)
 			break
@@ -141,27 +311,62 @@ func (f FontID) load(frects *[]uint32) error {
 
 	// Create images and reserve mapping for each rune
 
-	for x := 1; x < p.Bounds().Dx(); g++ {
-		w := 0
-		for x+w < p.Bounds().Dx() && p.Pix[x+w+h*p.Stride] != 0 {
-			w++
+	for s := 0; s < desc.Strips; s++ {
+		y, my := s*sh, s*sh+h // top and marker row of the strip
+		for x := 1; x < p.Bounds().Dx(); g++ {
+			w := 0
+			for x+w < p.Bounds().Dx() && p.Pix[x+w+my*p.Stride] != 0 {
+				w++
+			}
+			if w > maxw {
+				maxw = w
+			}
+			m := p.SubImage(image.Rect(x, y, x+w, my))
+			mm, ok := m.(*image.Paletted)
+			if !ok {
+				return errors.New("unexpected subimage in Loadfont")
+			}
+			gg := picture(mm)
+			if gg != PictureID(g) {
+				//TODO:
+			}
+			x += w
+			for x < p.Bounds().Dx() && p.Pix[x+my*p.Stride] == 0 {
+				x++
+			}
 		}
-		if w > maxw {
-			maxw = w
+	}
+
+	// Associate the glyphs with their runes
+
+	if desc.Runes != nil {
+		rr, err := parseRunes(desc.Runes)
+		if err != nil {
+			return internal.Wrap("in description of font "+fontPaths[f], err)
 		}
-		m := p.SubImage(image.Rect(x, 0, x+w, h))
-		mm, ok := m.(*image.Paletted)
-		if !ok {
-			return errors.New("unexpected subimage in Loadfont")
+		n := g - fonts[f].first
+		if int(n) != len(rr) {
+			return errors.New("impossible to load font " + fontPaths[f] + " (" +
+				strconv.Itoa(int(n)) + " glyphs for " + strconv.Itoa(len(rr)) + " runes)")
 		}
-		gg := picture(mm)
-		if gg != PictureID(g) {
-			//TODO:
+		fonts[f].runes = make(map[rune]uint16, len(rr))
+		for i, r := range rr {
+			if _, ok := fonts[f].runes[r]; !ok {
+				fonts[f].runes[r] = uint16(i)
+			}
 		}
-		x += w
-		for x < p.Bounds().Dx() && p.Pix[x+h*p.Stride] == 0 {
-			x++
+		// Without 0x7F or an explicit fallback, the first glyph is used
+		fb := fonts[f].runes[0x7F]
+		if desc.Fallback != "" {
+			r, _ := utf8.DecodeRuneInString(desc.Fallback)
+			var ok bool
+			fb, ok = fonts[f].runes[r]
+			if !ok {
+				return errors.New("impossible to load font " + fontPaths[f] +
+					` (fallback "` + desc.Fallback + `" not in runes)`)
+			}
 		}
+		fonts[f].fallback = fb
 	}
 
 	internal.Debug.Printf(
@@ -174,3 +379,27 @@ func (f FontID) load(frects *[]uint32) error {
 
 	return nil
 }
+
+// description loads the JSON file associated with the font, if there is one.
+func (f FontID) description() (fontDescription, error) {
+	desc := fontDescription{Strips: 1}
+	if f == 0 || fonts[f].image != nil {
+		return desc, nil
+	}
+
+	path := fontPaths[f] + ".json"
+	fl, err := open(fonts[f].fsys, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return desc, nil
+	}
+	if err != nil {
+		return desc, internal.Wrap(`while opening font description "`+path+`"`, err)
+	}
+	defer fl.Close()
+
+	d := json.NewDecoder(fl)
+	if err := d.Decode(&desc); err != nil {
+		return desc, internal.Wrap(`while parsing font description "`+path+`"`, err)
+	}
+	return desc, nil
+}
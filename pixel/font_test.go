@@ -0,0 +1,106 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParseRunes(t *testing.T) {
+	rr, err := parseRunes([]string{"a-d", "éè", "-", "А-Г", "x-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "abcdéè-АБВГx-"
+	if string(rr) != want {
+		t.Errorf("parseRunes: want %q, got %q", want, string(rr))
+	}
+
+	_, err = parseRunes([]string{"z-a"})
+	if err == nil {
+		t.Errorf("parseRunes: invalid range accepted")
+	}
+}
+
+func TestGlyph(t *testing.T) {
+	fonts = append(fonts, font{
+		first:    100,
+		runes:    map[rune]uint16{'a': 0, 'é': 1, 'Ж': 2},
+		fallback: 1,
+	})
+	defer func() { fonts = fonts[:len(fonts)-1] }()
+	f := FontID(len(fonts) - 1)
+
+	for _, c := range []struct {
+		r     rune
+		glyph uint16
+		has   bool
+	}{
+		{'a', 100, true},
+		{'é', 101, true},
+		{'Ж', 102, true},
+		{'b', 101, false},
+		{'\n', 101, false},
+	} {
+		if g := f.glyph(c.r); g != c.glyph {
+			t.Errorf("glyph of %q: want %d, got %d", c.r, c.glyph, g)
+		}
+		if h := f.Has(c.r); h != c.has {
+			t.Errorf("Has(%q): want %v, got %v", c.r, c.has, h)
+		}
+	}
+
+	if g := Monozela10.glyph('A'); g != fonts[0].first+'A'-' ' {
+		t.Errorf("glyph of 'A' in ASCII font: got %d", g)
+	}
+	if g := Monozela10.glyph('é'); g != fonts[0].first+0x7F-' ' {
+		t.Errorf("glyph of 'é' in ASCII font: got %d", g)
+	}
+}
+
+func TestFontFallback(t *testing.T) {
+	defer headless(t, XY{1, 1})()
+
+	// Two glyphs, 'a' (1 pixel wide) and 'b' (2 pixels wide)
+	f := pngFile(t, paletted(5, 3,
+		0, 2, 0, 2, 2,
+		1, 2, 0, 2, 0,
+		0, 1, 0, 1, 1))
+	fsys := fstest.MapFS{
+		"none.png":     f,
+		"none.json":    {Data: []byte(`{"Runes": ["ab"]}`)},
+		"b.png":        f,
+		"b.json":       {Data: []byte(`{"Runes": ["ab"], "Fallback": "b"}`)},
+		"missing.png":  f,
+		"missing.json": {Data: []byte(`{"Runes": ["ab"], "Fallback": "?"}`)},
+		"count.png":    f,
+		"count.json":   {Data: []byte(`{"Runes": ["abc"]}`)},
+	}
+
+	for _, c := range []struct {
+		path     string
+		fallback uint16
+	}{
+		{"none", 0},
+		{"b", 1},
+	} {
+		f := FontFS(fsys, c.path)
+		if err := f.load(nil); err != nil {
+			t.Fatal(err)
+		}
+		if fonts[f].fallback != c.fallback {
+			t.Errorf("font %s: want fallback %d, got %d", c.path, c.fallback, fonts[f].fallback)
+		}
+	}
+
+	if err := FontFS(fsys, "missing").load(nil); err == nil {
+		t.Errorf("missing fallback accepted")
+	}
+	if err := FontFS(fsys, "count").load(nil); err == nil {
+		t.Errorf("more runes than glyphs accepted")
+	}
+}
@@ -6,13 +6,12 @@ package pixel
 import (
 	"errors"
 	"image"
-	stdcolor "image/color"
 	"strings"
 	"unsafe"
 
 	"github.com/cozely/cozely/color"
 	"github.com/cozely/cozely/internal"
-	"github.com/cozely/cozely/x/atlas"
+	"github.com/cozely/cozely/window"
 	"github.com/cozely/cozely/x/gl"
 )
 
@@ -27,6 +26,7 @@ const (
 const (
 	layoutParameters = 0
 	layoutPictureMap = 1
+	layoutRemaps     = 2
 	layoutPictures   = 3
 )
 
@@ -40,11 +40,16 @@ const (
 var renderer = glRenderer{}
 
 type glRenderer struct {
-	// Canvas and filter drawing pipeline
-	drawPipeline  *gl.Pipeline
-	pictureMapTBO gl.BufferTexture
-	picturesTA    gl.TextureArray2D
-	drawUBO       gl.UniformBuffer
+	// Canvas and filter drawing pipelines
+	drawPipeline   *gl.Pipeline
+	filterPipeline *gl.Pipeline // same, without depth writes
+	pictureMapTBO  gl.BufferTexture
+	pictureMapCap  int // capacity of the TBO, in pictures
+	picturesTA     gl.TextureArray2D
+	pictureBins    int16 // number of layers in the texture array
+	remapsTBO      gl.BufferTexture
+	remapsCap      int // capacity of the TBO, in tables
+	drawUBO        gl.UniformBuffer
 
 	// Blitting pipeline
 	blitPipeline *gl.Pipeline
@@ -56,6 +61,8 @@ type glRenderer struct {
 	filterBuf gl.Framebuffer
 	filterTex gl.Texture2D
 	depthTex  gl.Renderbuffer
+	offscreen []glCanvas     // indexed by CanvasID (the first one is unused)
+	atlasBuf  gl.Framebuffer // to copy the off-screen canvases
 
 	// Palette
 	paletteSSBO gl.StorageBuffer
@@ -67,6 +74,13 @@ type glRenderer struct {
 	commands      []gl.DrawIndirectCommand
 	parametersTBO gl.BufferTexture
 	parameters    []int16
+	views         []view // cameras used during the frame
+}
+
+type glCanvas struct {
+	buf   gl.Framebuffer
+	tex   gl.Texture2D
+	depth gl.Renderbuffer
 }
 
 // Note: The uniform structs need to be at top level to pass cgo's pointer
@@ -83,13 +97,7 @@ var blitUniforms struct {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func init() {
-	internal.PixelSetup = setup
-	internal.PixelCleanup = cleanup
-	internal.PixelRender = render
-}
-
-func setup() error {
+func (a *glRenderer) setup() error {
 	// Prepare the palette
 
 	renderer.paletteSSBO = gl.NewStorageBuffer(uintptr(256*4*4), gl.DynamicStorage|gl.MapWrite)
@@ -101,6 +109,7 @@ func setup() error {
 
 	renderer.canvasBuf = gl.NewFramebuffer()
 	renderer.filterBuf = gl.NewFramebuffer()
+	renderer.atlasBuf = gl.NewFramebuffer()
 
 	renderer.commandsICBO = gl.NewIndirectBuffer(
 		uintptr(cap(renderer.commands))*unsafe.Sizeof(renderer.commands[0]),
@@ -126,6 +135,16 @@ func setup() error {
 		gl.DepthComparison(gl.GreaterOrEqual),
 	)
 
+	renderer.filterPipeline = gl.NewPipeline(
+		gl.VertexShader(strings.NewReader(drawVertexShader)),
+		gl.FragmentShader(strings.NewReader(drawFragmentShader)),
+		gl.CullFace(false, false),
+		gl.Topology(gl.TriangleStrip),
+		gl.DepthTest(true),
+		gl.DepthWrite(false),
+		gl.DepthComparison(gl.GreaterOrEqual),
+	)
+
 	renderer.drawUBO = gl.NewUniformBuffer(&drawUniforms, gl.DynamicStorage|gl.MapWrite)
 
 	renderer.clearQueued = true
@@ -142,32 +161,18 @@ func setup() error {
 
 	renderer.blitUBO = gl.NewUniformBuffer(&blitUniforms, gl.DynamicStorage|gl.MapWrite)
 
-	// Create texture atlas for pictures (and fonts glyphs)
-
-	pictures.atlas = atlas.New(1024, 1024)
-
-	err := loadAssets()
-	if err != nil {
-		return err
-	}
-
 	// Mappings Buffer
-	renderer.pictureMapTBO = gl.NewBufferTexture(pictures.mapping, gl.R16I, gl.StaticStorage)
+	renderer.pictureMapCap = 0
+	renderer.updateMapping()
 
 	// Create the pictures texture array
 	w, h := pictures.atlas.BinSize()
-	if pictures.atlas.BinCount() > 0 {
-		renderer.picturesTA = gl.NewTextureArray2D(1, gl.R8UI, int32(w), int32(h), int32(pictures.atlas.BinCount()))
+	renderer.pictureBins = pictures.atlas.BinCount()
+	if renderer.pictureBins > 0 {
+		renderer.picturesTA = gl.NewTextureArray2D(1, gl.R8UI, int32(w), int32(h), int32(renderer.pictureBins))
 	}
 	for i := int16(0); i < pictures.atlas.BinCount(); i++ {
-		m := image.NewPaletted(image.Rectangle{
-			Min: image.Point{0, 0},
-			Max: image.Point{int(w), int(h)},
-		},
-			stdcolor.Palette{},
-		)
-
-		err := pictures.atlas.Paint(i, m, pictPaint)
+		m, err := paintBin(i)
 		if err != nil {
 			return err
 		}
@@ -190,47 +195,124 @@ func setup() error {
 		renderer.picturesTA.SubImage(0, 0, 0, int32(i), m)
 	}
 
-	pictures.path = pictures.path[:2]
-	pictures.image = pictures.image[:2]
+	// Create the off-screen canvases
+	renderer.offscreen = renderer.offscreen[:0]
+	for i := range canvases.size {
+		renderer.newCanvas(CanvasID(i))
+	}
 
 	return gl.Err()
 }
 
-////////////////////////////////////////////////////////////////////////////////
+// newCanvas creates the framebuffer of an off-screen canvas.
+func (a *glRenderer) newCanvas(c CanvasID) {
+	if c == Screen {
+		a.offscreen = append(a.offscreen, glCanvas{})
+		return
+	}
 
-func cleanup() error {
-	// Palette
-	SetPalette(DefaultPalette)
-	palette.dirty = true
+	s := canvases.size[c]
+	o := glCanvas{
+		buf:   gl.NewFramebuffer(),
+		tex:   gl.NewTexture2D(1, gl.R8UI, int32(s.X), int32(s.Y)),
+		depth: gl.NewRenderbuffer(gl.Depth32F, int32(s.X), int32(s.Y)),
+	}
+	o.buf.Texture(gl.ColorAttachment0, o.tex, 0)
+	o.buf.Renderbuffer(gl.DepthAttachment, o.depth)
+	o.buf.DrawBuffer(gl.ColorAttachment0)
+	o.buf.ReadBuffer(gl.ColorAttachment0)
+
+	st := o.buf.CheckStatus(gl.DrawReadFramebuffer)
+	if st != gl.FramebufferComplete {
+		setErr(errors.New("pixel off-screen canvas creation: " + st.String()))
+	}
 
+	a.offscreen = append(a.offscreen, o)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a *glRenderer) cleanup() error {
 	// Canvases
 	renderer.depthTex.Delete()
 	renderer.canvasTex.Delete()
 	renderer.canvasBuf.Delete()
 	renderer.filterTex.Delete()
 	renderer.filterBuf.Delete()
+	for _, o := range renderer.offscreen[1:] {
+		o.depth.Delete()
+		o.tex.Delete()
+		o.buf.Delete()
+	}
+	renderer.offscreen = renderer.offscreen[:0]
+	renderer.atlasBuf.Delete()
 
 	// Display pipeline
 	renderer.drawPipeline.Delete()
 	renderer.drawPipeline = nil
+	renderer.filterPipeline.Delete()
+	renderer.filterPipeline = nil
 	renderer.drawUBO.Delete()
 
 	// Pictures
-	pictures.atlas = nil
-	pictures.mapping = pictures.mapping[:2]
 	renderer.pictureMapTBO.Delete()
 	renderer.picturesTA.Delete()
-
-	// Fonts
-	fonts = fonts[:1]
-	fontPaths = fontPaths[:1]
+	renderer.pictureBins = 0
+	renderer.remapsTBO.Delete()
+	renderer.remapsCap = 0
 
 	return gl.Err()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func adjustScreenTextures() {
+// update uploads the pictures loaded while running, extending the texture
+// array if new bins have been added to the atlas.
+func (a *glRenderer) update() {
+	a.updateMapping()
+
+	n := pictures.atlas.BinCount()
+	if n > a.pictureBins {
+		w, h := pictures.atlas.BinSize()
+		ta := gl.NewTextureArray2D(1, gl.R8UI, int32(w), int32(h), int32(n))
+		if a.pictureBins > 0 {
+			ta.CopyFrom(&a.picturesTA, int32(w), int32(h), int32(a.pictureBins))
+			a.picturesTA.Delete()
+		}
+		a.picturesTA = ta
+		a.pictureBins = n
+	}
+
+	for _, r := range pictures.pending {
+		m := pictures.mapping[r]
+		if m.w == 0 || m.h == 0 || pictures.image[r] == nil {
+			// Unloaded since
+			continue
+		}
+		a.picturesTA.SubImage(0, int32(m.x), int32(m.y), int32(m.bin), packed(pictures.image[r]))
+	}
+}
+
+// updateMapping uploads the mapping of all pictures, growing the buffer texture
+// if needed.
+func (a *glRenderer) updateMapping() {
+	if len(pictures.mapping) > a.pictureMapCap {
+		if a.pictureMapCap > 0 {
+			a.pictureMapTBO.Delete()
+		}
+		a.pictureMapCap = 2 * len(pictures.mapping)
+		a.pictureMapTBO = gl.NewBufferTexture(
+			uintptr(a.pictureMapCap)*unsafe.Sizeof(pictures.mapping[0]),
+			gl.R16I,
+			gl.DynamicStorage,
+		)
+	}
+	a.pictureMapTBO.SubData(pictures.mapping, 0)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a *glRenderer) adjust() {
 	renderer.depthTex.Delete()
 	renderer.depthTex = gl.NewRenderbuffer(gl.Depth32F, int32(screen.size.X), int32(screen.size.Y))
 
@@ -263,6 +345,45 @@ func adjustScreenTextures() {
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// snapshot reads back the canvas texture, with the filter applied.
+func (a *glRenderer) snapshot() *image.Paletted {
+	if a.drawPipeline == nil {
+		return nil
+	}
+
+	m := readTexture(a.canvasTex, screen.size)
+	applyFilter(m.Pix, readTexture(a.filterTex, screen.size).Pix)
+	return m
+}
+
+// snapshotCanvas reads back the texture of an off-screen canvas.
+func (a *glRenderer) snapshotCanvas(c CanvasID) *image.Paletted {
+	if int(c) >= len(a.offscreen) {
+		return nil
+	}
+	return readTexture(a.offscreen[c].tex, canvases.size[c])
+}
+
+// readTexture reads back a canvas texture. The rows are flipped, since OpenGL
+// stores the bottom of the canvas first.
+func readTexture(tex gl.Texture2D, size XY) *image.Paletted {
+	m := image.NewPaletted(image.Rect(0, 0, int(size.X), int(size.Y)), nil)
+	tex.GetImage(0, m)
+
+	r := make([]uint8, m.Stride)
+	for y := 0; y < m.Rect.Dy()/2; y++ {
+		t := m.Pix[y*m.Stride : (y+1)*m.Stride]
+		b := m.Pix[(m.Rect.Dy()-1-y)*m.Stride : (m.Rect.Dy()-y)*m.Stride]
+		copy(r, t)
+		copy(t, b)
+		copy(b, r)
+	}
+
+	return m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 // Clear sets the color of all pixels on the canvas; it also resets the filter
 // of all pixels.
 func (a *glRenderer) clear(c color.Index) {
@@ -280,7 +401,8 @@ func (a *glRenderer) command(c uint32, v uint32, n uint32, params ...int16) {
 	switch {
 
 	case l > 0 && c == (a.commands[l-1].BaseInstance>>24) &&
-		c != cmdLines && c != cmdTriangles:
+		c != cmdLines && c != cmdTriangles && c != cmdTilemap &&
+		(len(a.views) == 0 || a.views[len(a.views)-1].first < uint32(l)):
 
 		if c != cmdText {
 			// Collapse with previous draw command
@@ -312,6 +434,11 @@ func (a *glRenderer) command(c uint32, v uint32, n uint32, params ...int16) {
 		a.parameters = append(a.parameters, params...)
 	}
 
+	if internal.Config.Headless {
+		// No GPU buffers to resize
+		return
+	}
+
 	if ccap < cap(a.commands) {
 		a.commandsICBO.Delete()
 		a.commandsICBO = gl.NewIndirectBuffer(
@@ -332,18 +459,33 @@ func (a *glRenderer) command(c uint32, v uint32, n uint32, params ...int16) {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-// render executes all pending commands on the canvas. It is automatically called
-// by Display; the only reason to call it manually is to be able to read from it
-// before display.
-func render() error {
+func (a *glRenderer) render() error {
 	// Upload the current palette
 
 	if palette.dirty {
-		renderer.paletteSSBO.SubData(palette.colors[:], 0)
+		renderer.paletteSSBO.SubData(palette.shown[:], 0)
 		palette.dirty = false
 	}
 	renderer.paletteSSBO.Bind(0)
 
+	// Upload the remapping tables
+
+	if remaps.dirty {
+		if len(remaps.tables) > renderer.remapsCap {
+			if renderer.remapsCap > 0 {
+				renderer.remapsTBO.Delete()
+			}
+			renderer.remapsCap = 2 * len(remaps.tables)
+			renderer.remapsTBO = gl.NewBufferTexture(
+				uintptr(renderer.remapsCap)*unsafe.Sizeof(remaps.tables[0]),
+				gl.R8UI,
+				gl.DynamicStorage,
+			)
+		}
+		renderer.remapsTBO.SubData(remaps.tables, 0)
+		remaps.dirty = false
+	}
+
 	// Execute all pending commands
 
 	renderer.canvasBuf.Bind(gl.DrawFramebuffer)
@@ -355,35 +497,76 @@ func render() error {
 		renderer.clearQueued = false
 		renderer.canvasBuf.ClearColorUint(uint32(renderer.clearColor), 0, 0, 1)
 		renderer.canvasBuf.ClearDepth(-1.0)
+		renderer.filterBuf.ClearColorUint(uint32(NoRemap), 0, 0, 1)
+	}
+
+	for i, c := range canvases.clear {
+		if c < 0 {
+			continue
+		}
+		canvases.clear[i] = -1
+		renderer.offscreen[i].buf.ClearColorUint(uint32(c), 0, 0, 1)
+		renderer.offscreen[i].buf.ClearDepth(-1.0)
+		renderer.publish(CanvasID(i))
 	}
 
 	if len(renderer.commands) == 0 {
 		goto display
 	}
 
-	drawUniforms.PixelSize.X = 1.0 / float32(screen.size.X)
-	drawUniforms.PixelSize.Y = 1.0 / float32(screen.size.Y)
-	drawUniforms.CanvasMargin.X = int32(screen.margin.X)
-	drawUniforms.CanvasMargin.Y = int32(screen.margin.Y)
-	renderer.drawUBO.SubData(&drawUniforms, 0)
-
 	renderer.drawUBO.Bind(layoutScreen)
 	renderer.commandsICBO.Bind()
 	renderer.parametersTBO.Bind(layoutParameters)
 	renderer.pictureMapTBO.Bind(layoutPictureMap)
+	renderer.remapsTBO.Bind(layoutRemaps)
 	renderer.picturesTA.Bind(layoutPictures)
 
 	renderer.commandsICBO.SubData(renderer.commands, 0)
 	renderer.parametersTBO.SubData(renderer.parameters, 0)
-	gl.DrawIndirect(0, int32(len(renderer.commands)))
+
+	// One draw call for each camera (and target) used during the frame
+
+	eachView(func(v view, end uint32) {
+		switch {
+		case v.target != Screen:
+			renderer.offscreen[v.target].buf.Bind(gl.DrawFramebuffer)
+			renderer.drawPipeline.Bind()
+		case v.filter:
+			renderer.filterBuf.Bind(gl.DrawFramebuffer)
+			renderer.filterPipeline.Bind()
+		default:
+			renderer.canvasBuf.Bind(gl.DrawFramebuffer)
+			renderer.drawPipeline.Bind()
+		}
+		sz, m := v.target.frame()
+		gl.Viewport(0, 0, int32(sz.X), int32(sz.Y))
+		drawUniforms.PixelSize.X = 1.0 / float32(sz.X)
+		drawUniforms.PixelSize.Y = 1.0 / float32(sz.Y)
+		drawUniforms.CanvasMargin.X = int32(m.X + v.offset.X)
+		drawUniforms.CanvasMargin.Y = int32(m.Y + v.offset.Y)
+		renderer.drawUBO.SubData(&drawUniforms, 0)
+		gl.Enable(gl.ScissorTest)
+		gl.Scissor(
+			int32(v.clip.Min.X), int32(sz.Y)-int32(v.clip.Max.Y),
+			int32(v.clip.Dx()), int32(v.clip.Dy()),
+		)
+		gl.DrawIndirect(
+			uintptr(v.first)*unsafe.Sizeof(renderer.commands[0]),
+			int32(end-v.first),
+		)
+		gl.Disable(gl.ScissorTest)
+		if v.target != Screen {
+			renderer.publish(v.target)
+		}
+	})
+
 	renderer.commands = renderer.commands[:0]
 	renderer.parameters = renderer.parameters[:0]
 
 	// Display the canvas on the game window.
 
 display:
-	sz := screen.size.Times(screen.zoom)
-
+	renderer.views = renderer.views[:0]
 	blitUniforms.ScreenSize.X = float32(screen.size.X)
 	blitUniforms.ScreenSize.Y = float32(screen.size.Y)
 	renderer.blitUBO.SubData(&blitUniforms, 0)
@@ -392,11 +575,35 @@ display:
 	gl.DefaultFramebuffer.Bind(gl.DrawFramebuffer)
 	gl.Enable(gl.FramebufferSRGB)
 	gl.Disable(gl.Blend)
+	if screen.border != (window.XY{}) {
+		lb := screen.letterbox
+		gl.DefaultFramebuffer.ClearColor(0, lb.R, lb.G, lb.B, lb.A)
+	}
 	gl.Viewport(int32(screen.border.X), int32(screen.border.Y),
-		int32(screen.border.X+sz.X), int32(screen.border.Y+sz.Y))
+		int32(screen.display.X), int32(screen.display.Y))
 	renderer.blitUBO.Bind(0)
 	renderer.canvasTex.Bind(0)
+	renderer.filterTex.Bind(1)
+	renderer.remapsTBO.Bind(layoutRemaps)
 	gl.Draw(0, 4)
 
 	return gl.Err()
 }
+
+// publish copies an off-screen canvas to its picture in the texture atlas,
+// flipping it back to the orientation of the pictures.
+func (a *glRenderer) publish(c CanvasID) {
+	m := pictures.mapping[canvases.picture[c]]
+	if m.w == 0 || m.h == 0 || m.bin >= a.pictureBins {
+		// Unloaded
+		return
+	}
+	a.atlasBuf.TextureLayer(gl.ColorAttachment0, a.picturesTA, 0, int32(m.bin))
+	a.atlasBuf.DrawBuffer(gl.ColorAttachment0)
+	x, y, w, h := int32(m.x), int32(m.y), int32(m.w), int32(m.h)
+	a.offscreen[c].buf.Blit(a.atlasBuf,
+		0, 0, w, h,
+		x, y+h, x+w, y,
+		gl.ColorBufferBit, gl.Nearest,
+	)
+}
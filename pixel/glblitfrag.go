@@ -8,6 +8,7 @@ layout(origin_upper_left, pixel_center_integer) in vec4 gl_FragCoord;
 
 layout(binding = 0) uniform usampler2D screenTexture;
 layout(binding = 1) uniform usampler2D filterTexture;
+layout(binding = 2) uniform usamplerBuffer Remaps;
 
 layout(std430, binding = 0) buffer Palette {
 	vec4 Colours[256];
@@ -36,10 +37,11 @@ void main(void) {
 		discard;
 	}
 
+	if (f != 0) {
+		c = texelFetch(Remaps, int(f*256 + c)).x;
+	}
+
 	out_color = Colours[c];
-	// if (f != 0) {
-	// 	out_color = Colours[c-1];
-	// }
 }
 
 ////////////////////////////////////////////////////////////////////////////////
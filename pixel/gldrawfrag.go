@@ -11,6 +11,8 @@ const uint cmdPoint      = 4;
 const uint cmdLines      = 5;
 const uint cmdTriangles  = 6;
 const uint cmdBox        = 7;
+const uint cmdTilemap    = 8;
+const uint cmdSlice      = 9;
 
 ////////////////////////////////////////////////////////////////////////////////
 
@@ -30,6 +32,7 @@ layout(origin_upper_left, pixel_center_integer) in vec4 gl_FragCoord;
 
 ////////////////////////////////////////////////////////////////////////////////
 
+layout(binding = 2) uniform usamplerBuffer Remaps;
 layout(binding = 3) uniform usampler2DArray Pictures;
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -55,6 +58,16 @@ void main(void)
 		}
 		break;
 
+	case cmdPictureExt:
+	case cmdTilemap:
+		p = texelFetch(Pictures, ivec3(UV.x, UV.y, Bin), 0).x;
+		if (p == 0 || ColorIndex == 0) {
+			c = p;
+		} else {
+			c = texelFetch(Remaps, int(ColorIndex*256 + p)).x;
+		}
+		break;
+
 	case cmdText:
 		p = texelFetch(Pictures, ivec3(UV.x, UV.y, Bin), 0).x;
 		if (p == 0) {
@@ -101,6 +114,14 @@ void main(void)
 		}
 
 		break;
+
+	case cmdSlice:
+		vec2 uv = UV;
+		if (Flags == 0) {
+			uv = Box.xy + mod(floor(UV), Box.zw);
+		}
+		c = texelFetch(Pictures, ivec3(uv.x, uv.y, Bin), 0).x;
+		break;
 	}
 
 	if (c == 0) {
@@ -11,6 +11,8 @@ const uint cmdPoint      = 4;
 const uint cmdLines      = 5;
 const uint cmdTriangles  = 6;
 const uint cmdBox        = 7;
+const uint cmdTilemap    = 8;
+const uint cmdSlice      = 9;
 
 const vec2 corners[4] = vec2[4](
 	vec2(0, 0),
@@ -33,6 +35,12 @@ layout(binding = 1) uniform isamplerBuffer pictureMap;
 
 ////////////////////////////////////////////////////////////////////////////////
 
+const int flipX     = 0x01;
+const int flipY     = 0x02;
+const int transpose = 0x04;
+
+////////////////////////////////////////////////////////////////////////////////
+
 out gl_PerVertex {
 	vec4 gl_Position;
 };
@@ -57,6 +65,34 @@ float floatZ(int z) {
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// transformed computes the quad of an oriented and scaled picture.
+void transformed(int vertex, int m, int z, int x, int y, int o, int s) {
+	// Mapping of the picture
+	m *= 5;
+	Bin = texelFetch(pictureMap, m+0).r;
+	UV = vec2(texelFetch(pictureMap, m+1).r, texelFetch(pictureMap, m+2).r);
+	vec2 wh = vec2(texelFetch(pictureMap, m+3).r, texelFetch(pictureMap, m+4).r);
+	// Corner of the picture displayed at this vertex
+	vec2 t = corners[vertex];
+	if ((o & flipX) != 0) {
+		t.x = 1 - t.x;
+	}
+	if ((o & flipY) != 0) {
+		t.y = 1 - t.y;
+	}
+	vec2 sz = wh * s;
+	if ((o & transpose) != 0) {
+		t = t.yx;
+		sz = sz.yx;
+	}
+	// Quad
+	vec2 p = (CanvasMargin + vec2(x, y) + corners[vertex] * sz) * PixelSize;
+	gl_Position = vec4(p * vec2(2, -2) + vec2(-1,1), floatZ(z), 1);
+	UV += t * wh;
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 void main(void)
 {
 	Command = gl_BaseInstance >> 24;
@@ -65,7 +101,7 @@ void main(void)
 	int instance = gl_InstanceID;
 	int vertex = gl_VertexID;
 
-	int m, x, y, z, x2, y2, x3, y3, dx, dy;
+	int m, x, y, z, x2, y2, x3, y3, dx, dy, f;
 	uint c;
 	vec2 p, wh;
 	vec2 t, n, pts[4];
@@ -88,6 +124,37 @@ void main(void)
 		UV += corners[vertex] * wh;
 		break;
 
+	case cmdPictureExt:
+		// Parameters
+		offset = 6*instance;
+		m = texelFetch(parameters, param+0+offset).r;
+		z = texelFetch(parameters, param+1+offset).r;
+		x = texelFetch(parameters, param+2+offset).r;
+		y = texelFetch(parameters, param+3+offset).r;
+		f = texelFetch(parameters, param+4+offset).r;
+		c = texelFetch(parameters, param+5+offset).r;
+		transformed(vertex, m, z, x, y, f & 0x07, (f >> 8) & 0xFF);
+		ColorIndex = uint(c&0xFF);
+		break;
+
+	case cmdTilemap:
+		// Parameters
+		offset = 2*instance;
+		z = texelFetch(parameters, param+0).r;
+		x = texelFetch(parameters, param+1).r;
+		y = texelFetch(parameters, param+2).r;
+		dx = texelFetch(parameters, param+3).r;
+		dy = texelFetch(parameters, param+4).r;
+		x3 = texelFetch(parameters, param+5).r;
+		m = texelFetch(parameters, param+6+offset).r;
+		f = texelFetch(parameters, param+7+offset).r;
+		// Position of the tile
+		x += (instance % x3) * dx;
+		y += (instance / x3) * dy;
+		transformed(vertex, m, z, x, y, f & 0x07, 1);
+		ColorIndex = 0;
+		break;
+
 	case cmdText:
 	  // Parameters
 		offset = 2*instance;
@@ -186,6 +253,41 @@ void main(void)
 		// Color
 		ColorIndex = uint(c&0xFFFF);
 		break;
+
+	case cmdSlice:
+		offset = 11*instance;
+		// Parameters
+		m = texelFetch(parameters, param+0+offset).r;
+		z = texelFetch(parameters, param+1+offset).r;
+		x = texelFetch(parameters, param+2+offset).r;
+		y = texelFetch(parameters, param+3+offset).r;
+		x2 = texelFetch(parameters, param+4+offset).r;
+		y2 = texelFetch(parameters, param+5+offset).r;
+		x3 = texelFetch(parameters, param+6+offset).r;
+		y3 = texelFetch(parameters, param+7+offset).r;
+		dx = texelFetch(parameters, param+8+offset).r;
+		dy = texelFetch(parameters, param+9+offset).r;
+		f = texelFetch(parameters, param+10+offset).r;
+		// Mapping of the picture
+		m *= 5;
+		Bin = texelFetch(pictureMap, m+0).r;
+		t = vec2(texelFetch(pictureMap, m+1).r + x3, texelFetch(pictureMap, m+2).r + y3);
+		// Quad of the destination size
+		wh = vec2(x2, y2);
+		p = (CanvasMargin + vec2(x, y) + corners[vertex] * wh) * PixelSize;
+		gl_Position = vec4(p * vec2(2, -2) + vec2(-1,1), floatZ(z), 1);
+		// Source rectangle
+		Box = vec4(t, dx, dy);
+		Flags = uint(f);
+		if (f != 0) {
+			// Stretched
+			UV = t + corners[vertex] * vec2(dx, dy);
+		} else {
+			// Tiled (the fragment shader wraps the coordinates)
+			UV = corners[vertex] * wh;
+		}
+		ColorIndex = 0;
+		break;
 	}
 }
 `
@@ -0,0 +1,94 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"image"
+	stdcolor "image/color"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ColorMapping is the way the colors of image files (pictures and sprite
+// sheets) are converted to indices of the palette. Each picture and sheet uses
+// the mapping in effect when it is declared (see SetColorMapping).
+//
+// Images without a palette (e.g. truecolor or RGBA PNGs) are always mapped
+// onto the palette by nearest color (see NearestColor); the pixels with an
+// alpha below one half are transparent.
+type ColorMapping uint8
+
+// Available color mappings.
+const (
+	// KeepIndices uses the indices of paletted images as is, ignoring their
+	// palette (this is the default).
+	KeepIndices ColorMapping = iota
+	// NearestColors maps each color of the palette of paletted images onto the
+	// nearest color of the game palette. Note that the transparency of index 0 is
+	// lost, unless its alpha is below one half.
+	NearestColors
+)
+
+var colorMapping = KeepIndices
+
+// SetColorMapping changes the way the pictures and sprite sheets declared
+// afterward are converted to the palette; those already declared keep their
+// mapping. The conversion is done with the palette in use when they are loaded:
+// either the start of the framework, or the call to Reload.
+func SetColorMapping(m ColorMapping) {
+	colorMapping = m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// indexed returns a paletted version of a decoded image, according to a color
+// mapping.
+func indexed(img image.Image, cm ColorMapping) *image.Paletted {
+	if m, ok := img.(*image.Paletted); ok {
+		if cm == KeepIndices {
+			return m
+		}
+		t := make([]uint8, 256)
+		for i, c := range m.Palette {
+			t[i] = uint8(nearestStd(c))
+		}
+		n := &image.Paletted{
+			Pix:    make([]uint8, len(m.Pix)),
+			Stride: m.Stride,
+			Rect:   m.Rect,
+		}
+		for i, c := range m.Pix {
+			n.Pix[i] = t[c]
+		}
+		return n
+	}
+
+	b := img.Bounds()
+	n := image.NewPaletted(b, nil)
+	cache := map[stdcolor.Color]color.Index{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.At(x, y)
+			i, ok := cache[c]
+			if !ok {
+				i = nearestStd(c)
+				cache[c] = i
+			}
+			n.Pix[n.PixOffset(x, y)] = uint8(i)
+		}
+	}
+	return n
+}
+
+// nearestStd returns the palette index nearest to a color of the standard
+// library.
+func nearestStd(c stdcolor.Color) color.Index {
+	n := stdcolor.NRGBAModel.Convert(c).(stdcolor.NRGBA)
+	if n.A < 0x80 {
+		return 0
+	}
+	return NearestColor(color.SRGB8{n.R, n.G, n.B})
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"bytes"
+	"image"
+	stdcolor "image/color"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestColorMapping(t *testing.T) {
+	defer func() {
+		SetColorMapping(KeepIndices)
+		SetPalette(DefaultPalette)
+	}()
+
+	SetPalette(color.Palette{
+		Colors: []color.LRGBA{
+			color.LRGBAof(color.SRGB8{0xFF, 0x00, 0x00}),
+			color.LRGBAof(color.SRGB8{0x00, 0xC0, 0x00}),
+			color.LRGBAof(color.SRGB8{0x20, 0x20, 0xFF}),
+		},
+	})
+
+	for _, c := range []struct {
+		color color.Color
+		want  color.Index
+	}{
+		{color.SRGB8{0xFF, 0x00, 0x00}, 1},
+		{color.SRGB8{0xE0, 0x30, 0x10}, 1},
+		{color.SRGB8{0x10, 0xFF, 0x10}, 2},
+		{color.SRGB8{0x00, 0x00, 0x80}, 3},
+		{color.SRGB8{0x00, 0x00, 0x00}, 2}, // not one of the unused indices
+		{color.SRGBA8{0xFF, 0x00, 0x00, 0x40}, 0},
+	} {
+		if got := NearestColor(c.color); got != c.want {
+			t.Errorf("nearest color of %v: want %d, got %d", c.color, c.want, got)
+		}
+	}
+
+	// Truecolor image
+
+	rgba := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	rgba.Set(0, 0, stdcolor.NRGBA{0x00, 0xFF, 0x00, 0xFF})
+	rgba.Set(1, 0, stdcolor.NRGBA{0xF0, 0x10, 0x10, 0xFF})
+	rgba.Set(2, 0, stdcolor.NRGBA{0x00, 0x00, 0xFF, 0x10})
+	rgba.Set(3, 0, stdcolor.NRGBA{0x30, 0x30, 0xF0, 0xFF})
+	truecolor := pngFile(t, rgba).Data
+
+	// Paletted image with its own palette
+
+	pal := image.NewPaletted(image.Rect(0, 0, 4, 1), stdcolor.Palette{
+		stdcolor.NRGBA{0, 0, 0, 0},
+		stdcolor.NRGBA{0x00, 0x00, 0xFF, 0xFF},
+		stdcolor.NRGBA{0xFF, 0x00, 0x00, 0xFF},
+		stdcolor.NRGBA{0x00, 0xFF, 0x00, 0xFF},
+	})
+	pal.Pix = []uint8{1, 2, 3, 0}
+	file := pngFile(t, pal)
+
+	pp := []PictureID{
+		PictureReader(bytes.NewReader(truecolor)),
+		PictureReader(bytes.NewReader(file.Data)),
+	}
+	SetColorMapping(NearestColors)
+	pp = append(pp, PictureReader(bytes.NewReader(file.Data)))
+
+	// Files are loaded later, with the mapping in effect at declaration
+
+	fsys := fstest.MapFS{"pal.png": file}
+	pp = append(pp, PictureFS(fsys, "pal"))
+	sheet := SheetFS(fsys, "pal", XY{4, 1})
+	SetColorMapping(KeepIndices)
+	pp = append(pp, PictureFS(fsys, "pal"))
+
+	defer headless(t, XY{24, 1})()
+	pp = append(pp, sheet.Frame(0))
+
+	Clear(0)
+	for i, p := range pp {
+		p.Paint(0, XY{int16(4 * i), 0})
+	}
+	render()
+
+	want := "210312303120312012303120"
+	if got := dump(software.canvas.Pix, 24); got != want {
+		t.Errorf("mapped pictures: want %s, got %s", want, got)
+	}
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+}
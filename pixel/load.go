@@ -5,6 +5,10 @@ package pixel
 
 import (
 	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 
 	"github.com/cozely/cozely/internal"
 )
@@ -13,7 +17,7 @@ import (
 
 func loadAssets() error {
 	if internal.Running {
-		return errors.New("loading graphics while running not implemented")
+		return errors.New("pixel assets loading: the framework is already running")
 	}
 
 	prects := []uint32{} //TODO: move with pictures.image
@@ -36,6 +40,15 @@ func loadAssets() error {
 		len(pictures.path)-c,
 	)
 
+	// Load all sprite sheets (they declare one picture per frame)
+
+	for i := range sheets {
+		err := SheetID(i).load()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Load all pictures
 
 	for i := range pictures.path {
@@ -48,7 +61,7 @@ func loadAssets() error {
 
 	// Pack them into a texture atlas
 
-	pictures.atlas.Pack(prects, pictSize, pictPut)
+	pack(prects)
 
 	internal.Debug.Printf(
 		"Packed %d pictures in %d bins (%.1fkB unused)\n",
@@ -59,3 +72,21 @@ func loadAssets() error {
 
 	return nil
 }
+
+////////////////////////////////////////////////////////////////////////////////
+
+// open returns the content of an asset file. If fsys is nil, the file is
+// searched relative to the application path; otherwise, name is a path inside
+// fsys.
+func open(fsys fs.FS, name string) (io.ReadCloser, error) {
+	if fsys != nil {
+		return fsys.Open(name)
+	}
+
+	path := filepath.FromSlash(internal.Path + name)
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
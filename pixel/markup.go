@@ -0,0 +1,133 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// textStyle is the part of the cursor state that can be changed by markup.
+type textStyle struct {
+	color color.Index
+	font  FontID
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// each calls f for every rune of s that should be displayed, with the offset
+// following it. If markup is enabled, the tags are applied to the cursor.
+func (a *Cursor) each(s string, f func(r rune, end int)) {
+	for i := 0; i < len(s); {
+		n, tag := a.next(s[i:])
+		switch {
+		case !tag:
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			f(r, i+n)
+		case s[i+1] == '{':
+			f('{', i+n)
+		default:
+			a.tag(s[i+1 : i+n-1])
+		}
+		i += n
+	}
+}
+
+// skip applies all the tags of s to the cursor, without displaying anything.
+func (a *Cursor) skip(s string) {
+	a.each(s, func(rune, int) {})
+}
+
+// fields splits s around each sequence of white space (outside of the tags).
+func (a *Cursor) fields(s string) []string {
+	ff := []string{}
+	start := -1
+	for i := 0; i < len(s); {
+		n, tag := a.next(s[i:])
+		r, _ := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case !tag && unicode.IsSpace(r):
+			if start >= 0 {
+				ff = append(ff, s[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+		i += n
+	}
+	if start >= 0 {
+		ff = append(ff, s[start:])
+	}
+	return ff
+}
+
+// next returns the length (in bytes) of the first element of s, and true if
+// this element is a valid tag or the escape sequence.
+func (a *Cursor) next(s string) (n int, tag bool) {
+	if a.Markup && s[0] == '{' {
+		if len(s) > 1 && s[1] == '{' {
+			return 2, true
+		}
+		j := strings.IndexAny(s, "}\n")
+		if j > 0 && s[j] == '}' {
+			c := *a
+			if c.tag(s[1:j]) {
+				return j + 1, true
+			}
+		}
+	}
+	_, n = utf8.DecodeRuneInString(s)
+	return n, false
+}
+
+// tag applies a tag (given without its braces) to the cursor. It returns false
+// if the tag is not recognized.
+func (a *Cursor) tag(t string) bool {
+	k, v := t, ""
+	i := strings.IndexByte(t, ':')
+	if i >= 0 {
+		k, v = t[:i], t[i+1:]
+	}
+
+	switch k {
+	case "c":
+		if i < 0 {
+			a.Color = a.base.color
+			return true
+		}
+		if n, err := strconv.ParseUint(v, 10, 8); err == nil {
+			a.Color = color.Index(n)
+			return true
+		}
+		if c, ok := palette.byName[v]; ok {
+			a.Color = c
+			return true
+		}
+
+	case "f":
+		if i < 0 {
+			a.Font = a.base.font
+			return true
+		}
+		if n, err := strconv.ParseUint(v, 10, 8); err == nil && int(n) < len(fonts) {
+			a.Font = FontID(n)
+			return true
+		}
+		for f, p := range fontPaths {
+			if p == v {
+				a.Font = FontID(f)
+				return true
+			}
+		}
+	}
+
+	return false
+}
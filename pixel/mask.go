@@ -0,0 +1,186 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+	"image"
+
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Mask is a copy of the shape of a picture, kept on the CPU for collision
+// detection: each pixel is either solid or empty. The overlap tests are
+// pixel-exact, and use the same coordinates as painting (i.e. the position of
+// the top-left corner of the picture on the canvas).
+type Mask struct {
+	size  XY
+	words int      // number of words per row
+	bits  []uint64 // bit i of word w of a row is column 64*w+i
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewMask returns the collision mask of a picture: the pixels of color index
+// zero are empty, and all others are solid.
+//
+// The pixels come from the image file (or the image given at declaration), so
+// this can be done before the picture is loaded. Note that the mask of an
+// off-screen canvas does not reflect what has been drawn on it.
+func NewMask(p PictureID) *Mask {
+	return NewMaskExt(p, Upright, 1)
+}
+
+// NewMaskExt returns the collision mask of a picture as painted by PaintExt,
+// with an orientation and an integer scale.
+func NewMaskExt(p PictureID, o Orientation, scale int16) *Mask {
+	if scale < 1 || scale > 0xFF {
+		setErr(errors.New("pixel mask creation: invalid scale"))
+		return &Mask{}
+	}
+	img, err := p.pixels()
+	if err != nil {
+		setErr(internal.Wrap("pixel mask creation", err))
+		return &Mask{}
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if o&transpose != 0 {
+		w, h = h, w
+	}
+	s := int(scale)
+	m := newMask(XY{int16(w * s), int16(h * s)})
+	for j := 0; j < h*s; j++ {
+		for i := 0; i < w*s; i++ {
+			u, v := i/s, j/s
+			if o&flipX != 0 {
+				u = w - 1 - u
+			}
+			if o&flipY != 0 {
+				v = h - 1 - v
+			}
+			if o&transpose != 0 {
+				u, v = v, u
+			}
+			if img.ColorIndexAt(b.Min.X+u, b.Min.Y+v) != 0 {
+				m.bits[j*m.words+i/64] |= 1 << uint(i%64)
+			}
+		}
+	}
+	return m
+}
+
+func newMask(size XY) *Mask {
+	w := (int(size.X) + 63) / 64
+	return &Mask{
+		size:  size,
+		words: w,
+		bits:  make([]uint64, w*int(size.Y)),
+	}
+}
+
+// pixels returns the image of a picture, even if it has been released after
+// upload.
+func (p PictureID) pixels() (*image.Paletted, error) {
+	if int(p) >= len(pictures.image) || p == noPicture {
+		return nil, errors.New("invalid picture")
+	}
+	if p == MouseCursor {
+		return &mousecursor, nil
+	}
+	if m := pictures.image[p]; m != nil {
+		return m, nil
+	}
+	if pictures.path[p] == "" {
+		return nil, errors.New("picture without image")
+	}
+	m, err := p.decode()
+	if err == nil && m == nil {
+		err = errors.New("unknown image format")
+	}
+	return m, err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Size returns the size of the mask.
+func (m *Mask) Size() XY {
+	return m.size
+}
+
+// Solid returns true if the pixel at coordinates p (relative to the top-left
+// corner of the mask) is solid. Pixels outside of the mask are empty.
+func (m *Mask) Solid(p XY) bool {
+	if p.X < 0 || p.Y < 0 || p.X >= m.size.X || p.Y >= m.size.Y {
+		return false
+	}
+	return m.bits[int(p.Y)*m.words+int(p.X)/64]&(1<<uint(p.X%64)) != 0
+}
+
+// Overlaps returns true if at least one solid pixel of the mask, placed at pos,
+// is on a solid pixel of the other mask, placed at opos.
+func (m *Mask) Overlaps(pos XY, o *Mask, opos XY) bool {
+	x1, y1 := max16(pos.X, opos.X), max16(pos.Y, opos.Y)
+	x2 := min16(pos.X+m.size.X, opos.X+o.size.X)
+	y2 := min16(pos.Y+m.size.Y, opos.Y+o.size.Y)
+	for y := y1; y < y2; y++ {
+		for x := int(x1); x < int(x2); x += 64 {
+			r := m.row(int(y-pos.Y), x-int(pos.X)) & o.row(int(y-opos.Y), x-int(opos.X))
+			if n := int(x2) - x; n < 64 {
+				r &= 1<<uint(n) - 1
+			}
+			if r != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OverlapsBox returns true if at least one solid pixel of the mask, placed at
+// pos, is inside a box (p1 and p2 are opposite corners, both included, as in
+// Box).
+func (m *Mask) OverlapsBox(pos XY, p1, p2 XY) bool {
+	if p2.X < p1.X {
+		p1.X, p2.X = p2.X, p1.X
+	}
+	if p2.Y < p1.Y {
+		p1.Y, p2.Y = p2.Y, p1.Y
+	}
+	x1, y1 := max16(pos.X, p1.X), max16(pos.Y, p1.Y)
+	x2 := min16(pos.X+m.size.X, p2.X+1)
+	y2 := min16(pos.Y+m.size.Y, p2.Y+1)
+	for y := y1; y < y2; y++ {
+		for x := int(x1); x < int(x2); x += 64 {
+			r := m.row(int(y-pos.Y), x-int(pos.X))
+			if n := int(x2) - x; n < 64 {
+				r &= 1<<uint(n) - 1
+			}
+			if r != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// row returns the 64 pixels of a row of the mask starting at column x (which
+// doesn't have to be aligned); bit i is column x+i.
+func (m *Mask) row(y, x int) uint64 {
+	r := m.bits[y*m.words : (y+1)*m.words]
+	word := func(w int) uint64 {
+		if w < 0 || w >= len(r) {
+			return 0
+		}
+		return r[w]
+	}
+	w, s := x>>6, uint(x&63)
+	if s == 0 {
+		return word(w)
+	}
+	return word(w)>>s | word(w+1)<<(64-s)
+}
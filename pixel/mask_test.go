@@ -0,0 +1,109 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestMask(t *testing.T) {
+	internal.Config.Headless = true
+	defer func() { internal.Config.Headless = false }()
+
+	plus := PictureImage(paletted(3, 3,
+		0, 1, 0,
+		1, 1, 1,
+		0, 1, 0))
+	square := PictureImage(paletted(2, 2, 5, 5, 5, 5))
+	line := make([]uint8, 70)
+	line[66] = 1
+	wide := PictureImage(paletted(70, 1, line...))
+	l := PictureImage(paletted(2, 1, 1, 0))
+
+	// A picture loaded from a file, whose image is released after upload
+	file := PictureFS(fstest.MapFS{"dot.png": pngFile(t, paletted(2, 1, 0, 3))}, "dot")
+
+	if err := setup(); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	mp, ms, mw := NewMask(plus), NewMask(square), NewMask(wide)
+
+	if !mp.Solid(XY{1, 0}) || mp.Solid(XY{0, 0}) || mp.Solid(XY{-1, 1}) || mp.Solid(XY{3, 1}) {
+		t.Errorf("wrong solid pixels in plus mask")
+	}
+
+	for _, c := range []struct {
+		a    *Mask
+		apos XY
+		b    *Mask
+		bpos XY
+		want bool
+	}{
+		{mp, XY{0, 0}, ms, XY{2, 2}, false},
+		{mp, XY{0, 0}, ms, XY{2, 1}, true},
+		{ms, XY{2, 1}, mp, XY{0, 0}, true},
+		{mp, XY{-5, -5}, ms, XY{-4, -3}, true},
+		{mp, XY{0, 0}, ms, XY{3, 0}, false},
+		{mw, XY{0, 0}, ms, XY{66, -1}, true},
+		{mw, XY{0, 0}, ms, XY{67, -1}, false},
+		{mw, XY{-3, 0}, ms, XY{62, 0}, true},
+		{mw, XY{-3, 0}, ms, XY{60, 0}, false},
+		{ms, XY{62, 0}, mw, XY{-3, 0}, true},
+	} {
+		if got := c.a.Overlaps(c.apos, c.b, c.bpos); got != c.want {
+			t.Errorf("overlap at %v and %v: want %v, got %v", c.apos, c.bpos, c.want, got)
+		}
+	}
+
+	for _, c := range []struct {
+		pos, p1, p2 XY
+		want        bool
+	}{
+		{XY{10, 10}, XY{10, 10}, XY{10, 10}, false},
+		{XY{10, 10}, XY{11, 10}, XY{11, 10}, true},
+		{XY{10, 10}, XY{20, 0}, XY{12, 11}, true},
+		{XY{10, 10}, XY{13, 0}, XY{20, 20}, false},
+		{XY{10, 10}, XY{0, 0}, XY{9, 20}, false},
+	} {
+		if got := mp.OverlapsBox(c.pos, c.p1, c.p2); got != c.want {
+			t.Errorf("overlap of box %v-%v: want %v, got %v", c.p1, c.p2, c.want, got)
+		}
+	}
+
+	// Orientation and scale, as in PaintExt
+
+	r := NewMaskExt(l, Rotate90, 2)
+	if r.Size() != (XY{2, 4}) || !r.Solid(XY{1, 1}) || r.Solid(XY{1, 2}) {
+		t.Errorf("rotated mask: size %v", r.Size())
+	}
+	f := NewMaskExt(l, FlipX, 1)
+	if f.Solid(XY{0, 0}) || !f.Solid(XY{1, 0}) {
+		t.Errorf("flipped mask: wrong pixels")
+	}
+
+	// File pictures
+
+	if pictures.image[file] != nil {
+		t.Errorf("image of file picture not released")
+	}
+	mf := NewMask(file)
+	if mf.Size() != (XY{2, 1}) || mf.Solid(XY{0, 0}) || !mf.Solid(XY{1, 0}) {
+		t.Errorf("mask of file picture: size %v", mf.Size())
+	}
+
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+	NewMaskExt(l, Upright, 0)
+	if Err() == nil {
+		t.Errorf("invalid scale accepted")
+	}
+}
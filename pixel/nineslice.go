@@ -0,0 +1,75 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A NineSlice describes how to paint a picture at any size, e.g. for window
+// frames and buttons. The picture is cut in nine slices by two vertical and two
+// horizontal lines: the four corners are painted as is, while the edges and the
+// center are either tiled or stretched to fill the rest of the rectangle.
+type NineSlice struct {
+	Picture PictureID
+	// The center slice of the picture (Max excluded); the corners are the parts
+	// outside of it.
+	Min, Max XY
+	// By default the edges and the center are tiled; these flags stretch them
+	// instead.
+	StretchEdges, StretchCenter bool
+}
+
+// Paint queues a GPU command to paint the nine-slice on a rectangle of the
+// canvas. The size should not be smaller than the corners.
+func (n NineSlice) Paint(layer int16, pos, size XY) {
+	s := n.Picture.Size()
+	if s.Null() {
+		// Not loaded
+		return
+	}
+
+	// Columns and rows of the slices, in the picture and on the canvas
+	src := [4]XY{{}, n.Min, n.Max, s}
+	dst := [4]XY{
+		pos,
+		pos.Plus(n.Min),
+		pos.Plus(size).Minus(s.Minus(n.Max)),
+		pos.Plus(size),
+	}
+	if dst[2].X < dst[1].X {
+		dst[2].X = dst[1].X
+	}
+	if dst[2].Y < dst[1].Y {
+		dst[2].Y = dst[1].Y
+	}
+
+	prm := make([]int16, 0, 9*11) //TODO: remove alloc
+	for j := 0; j < 3; j++ {
+		for i := 0; i < 3; i++ {
+			st := n.StretchEdges
+			if i == 1 && j == 1 {
+				st = n.StretchCenter
+			}
+			f := int16(0)
+			if st {
+				f = 1
+			}
+			o := XY{src[i].X, src[j].Y}
+			sz := XY{src[i+1].X - src[i].X, src[j+1].Y - src[j].Y}
+			p := XY{dst[i].X, dst[j].Y}
+			d := XY{dst[i+1].X - dst[i].X, dst[j+1].Y - dst[j].Y}
+			if sz.X <= 0 || sz.Y <= 0 || d.X <= 0 || d.Y <= 0 {
+				continue
+			}
+			prm = append(prm,
+				int16(n.Picture), layer,
+				p.X, p.Y, d.X, d.Y,
+				o.X, o.Y, sz.X, sz.Y,
+				f)
+		}
+	}
+	if len(prm) == 0 {
+		return
+	}
+	renderer.command(cmdSlice, 4, uint32(len(prm)/11), prm...)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"image"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestNineSlice(t *testing.T) {
+	m := image.NewPaletted(image.Rect(0, 0, 4, 4), nil)
+	m.Pix = []uint8{
+		1, 2, 3, 4,
+		5, 6, 7, 8,
+		9, 10, 11, 12,
+		13, 14, 15, 1,
+	}
+	p := PictureImage(m)
+
+	defer headless(t, XY{7, 6})()
+
+	for _, c := range []struct {
+		name  string
+		slice NineSlice
+		size  XY
+		want  string
+	}{
+		{"tiled", NineSlice{Picture: p, Min: XY{1, 1}, Max: XY{3, 3}}, XY{7, 5},
+			"1232324\n5676768\n9ABABAC\n5676768\nDEFEFE1\n0000000"},
+		{"stretched", NineSlice{Picture: p, Min: XY{1, 1}, Max: XY{3, 3}, StretchEdges: true, StretchCenter: true}, XY{7, 5},
+			"1223334\n5667778\n9AABBBC\n9AABBBC\nDEEFFF1\n0000000"},
+		{"mixed", NineSlice{Picture: p, Min: XY{1, 1}, Max: XY{3, 3}, StretchEdges: true}, XY{6, 6},
+			"1223340\n5676780\n5ABAB80\n96767C0\n9ABABC0\nDEEFF10"},
+		{"small", NineSlice{Picture: p, Min: XY{1, 1}, Max: XY{3, 3}}, XY{2, 3},
+			"1400000\n5800000\nD100000\n0000000\n0000000\n0000000"},
+	} {
+		Clear(0)
+		c.slice.Paint(0, XY{0, 0}, c.size)
+		render()
+		if got := dump(software.canvas.Pix, 7); got != c.want {
+			t.Errorf("%s:\nwant:\n%s\ngot:\n%s", c.name, c.want, got)
+		}
+	}
+}
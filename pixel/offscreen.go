@@ -0,0 +1,169 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+	"image"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CanvasID is the ID to handle off-screen canvases. An off-screen canvas
+// accepts the same drawing commands as the screen, but is not displayed:
+// instead, its content is available as a picture, which can be painted on the
+// screen (or on other canvases) like any other picture. This can be used to
+// pre-compose complex scenes, or to cache parts of the interface.
+//
+// Off-screen canvases have no filter and no margin. Like the screen, they keep
+// their content from one frame to the next, until cleared.
+type CanvasID uint16
+
+const (
+	maxCanvasID = 0xFFFF
+	noCanvas    = CanvasID(maxCanvasID)
+)
+
+// Screen is the canvas displayed on the window.
+const Screen = CanvasID(0)
+
+var canvases = struct {
+	size    []XY
+	picture []PictureID
+	clear   []int16 // color of the pending clear, or -1
+}{
+	size:    []XY{{}},
+	picture: []PictureID{noPicture},
+	clear:   []int16{-1},
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Canvas declares a new off-screen canvas, and returns its ID. Its size cannot
+// exceed the size of the texture atlas (1024x1024). The canvas is initially
+// cleared with color 0, i.e. it is transparent when painted.
+func Canvas(size XY) CanvasID {
+	if size.X < 1 || size.Y < 1 || size.X > atlasSize || size.Y > atlasSize {
+		setErr(errors.New("pixel canvas declaration: invalid size"))
+		return noCanvas
+	}
+	if len(canvases.size) >= maxCanvasID {
+		setErr(errors.New("pixel canvas declaration: too many canvases"))
+		return noCanvas
+	}
+
+	p := picture(image.NewPaletted(image.Rect(0, 0, int(size.X), int(size.Y)), nil))
+	if p == noPicture {
+		return noCanvas
+	}
+
+	canvases.size = append(canvases.size, size)
+	canvases.picture = append(canvases.picture, p)
+	canvases.clear = append(canvases.clear, 0)
+	c := CanvasID(len(canvases.size) - 1)
+
+	if internal.Running {
+		if internal.Config.Headless {
+			software.newCanvas(c)
+		} else {
+			renderer.newCanvas(c)
+		}
+	}
+	return c
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Use redirects all drawing commands queued after it to the canvas, until the
+// end of the frame or the next call to Use. The camera is reset.
+//
+// The screen is the default target at the start of each frame; use Screen.Use
+// to return to it. A canvas can paint its own picture: it shows the content of
+// the canvas as of the previous render.
+func (c CanvasID) Use() {
+	if int(c) >= len(canvases.size) {
+		setErr(errors.New("pixel canvas use: invalid canvas ID"))
+		return
+	}
+	setView(canvasView(c))
+}
+
+// Clear sets the color of all pixels of the canvas. For off-screen canvases,
+// it is done at the start of the next render, before any drawing command
+// (see also the Clear function).
+func (c CanvasID) Clear(i color.Index) {
+	if c == Screen {
+		Clear(i)
+		return
+	}
+	if int(c) >= len(canvases.size) {
+		setErr(errors.New("pixel canvas clear: invalid canvas ID"))
+		return
+	}
+	canvases.clear[c] = int16(i)
+}
+
+// Size returns the size of the canvas, in canvas pixels (for the screen, this
+// includes the margin around the resolution).
+func (c CanvasID) Size() XY {
+	if int(c) >= len(canvases.size) {
+		setErr(errors.New("pixel canvas size: invalid canvas ID"))
+		return XY{}
+	}
+	s, _ := c.frame()
+	return s
+}
+
+// Picture returns the picture showing the content of an off-screen canvas. It
+// is updated by each render.
+func (c CanvasID) Picture() PictureID {
+	if c == Screen || int(c) >= len(canvases.size) {
+		setErr(errors.New("pixel canvas picture: invalid canvas ID"))
+		return noPicture
+	}
+	return canvases.picture[c]
+}
+
+// Snapshot returns a copy of the canvas, as of the last render, associated
+// with the current palette. For the screen, this is the same as the Snapshot
+// function.
+func (c CanvasID) Snapshot() *image.Paletted {
+	if c == Screen {
+		return Snapshot()
+	}
+	if int(c) >= len(canvases.size) {
+		setErr(errors.New("pixel canvas snapshot: invalid canvas ID"))
+		return nil
+	}
+
+	var m *image.Paletted
+	if internal.Config.Headless {
+		m = software.snapshotCanvas(c)
+	} else {
+		m = renderer.snapshotCanvas(c)
+	}
+	if m == nil {
+		setErr(errors.New("pixel canvas snapshot: canvas not available"))
+		return nil
+	}
+	m.Palette = stdPalette()
+	return m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// frame returns the size of a drawing target, and the margin between its
+// top-left corner and the origin of the drawing commands.
+func (c CanvasID) frame() (size, margin XY) {
+	if c == Screen {
+		return screen.size, screen.margin
+	}
+	if int(c) >= len(canvases.size) {
+		return XY{}, XY{}
+	}
+	return canvases.size[c], XY{}
+}
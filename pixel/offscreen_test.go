@@ -0,0 +1,100 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import "testing"
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOffscreen(t *testing.T) {
+	c := Canvas(XY{3, 2})
+	defer headless(t, XY{6, 4})()
+
+	if c.Size() != (XY{3, 2}) || c.Picture().Size() != (XY{3, 2}) {
+		t.Fatalf("unexpected canvas size %v", c.Size())
+	}
+
+	// Draw in the canvas, then paint it twice on the screen
+
+	Clear(1)
+	c.Clear(2)
+	c.Use()
+	Point(3, 0, XY{0, 0})
+	Lines(4, 0, XY{0, 1}, XY{2, 1})
+	Point(5, 0, XY{5, 0}) // outside
+	Screen.Use()
+	c.Picture().Paint(0, XY{0, 0})
+	c.Picture().Paint(0, XY{3, 2})
+	Point(6, 1, XY{5, 0})
+	render()
+
+	want := "322116\n444111\n111322\n111444"
+	if got := dump(software.canvas.Pix, 6); got != want {
+		t.Errorf("screen:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+	want = "322\n444"
+	if got := dump(c.Snapshot().Pix, 3); got != want {
+		t.Errorf("canvas:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+
+	// The content is kept until cleared; color 0 is transparent
+
+	c.Use()
+	cam := Camera{Scroll: XY{-1, 0}}
+	cam.Use(1, 0)
+	Point(7, 0, XY{0, 0})
+	render()
+	if got := dump(c.Snapshot().Pix, 3); got != "372\n444" {
+		t.Errorf("canvas not kept or camera not applied:\n%s", got)
+	}
+
+	c.Clear(0)
+	Clear(1)
+	Box(8, 8, -1, 0, XY{0, 0}, XY{5, 3})
+	c.Use()
+	Point(9, 0, XY{1, 1})
+	Screen.Use()
+	c.Picture().Paint(0, XY{1, 1})
+	render()
+	want = "888888\n888888\n889888\n888888"
+	if got := dump(software.canvas.Pix, 6); got != want {
+		t.Errorf("transparency:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+
+	// A canvas painting its own picture uses the previous render
+
+	c.Use()
+	c.Picture().Paint(0, XY{-1, -1})
+	render()
+	if got := dump(c.Snapshot().Pix, 3); got != "900\n090" {
+		t.Errorf("canvas painting itself:\n%s", got)
+	}
+
+	c.Use()
+	UseFilter()
+	if err := Err(); err == nil {
+		t.Errorf("filter accepted on an off-screen canvas")
+	}
+
+	// A failed declaration returns an ID outside of the registry
+
+	invalid := Canvas(XY{0, 2})
+	if invalid == Screen || Err() == nil {
+		t.Errorf("invalid declaration: got %d", invalid)
+	}
+	if invalid.Size() != (XY{}) || Err() == nil {
+		t.Errorf("size of an invalid canvas: no error")
+	}
+	invalid.Use()
+	if Err() == nil {
+		t.Errorf("use of an invalid canvas: no error")
+	}
+	invalid.Clear(1)
+	if Err() == nil {
+		t.Errorf("clear of an invalid canvas: no error")
+	}
+	if invalid.Picture() != noPicture || Err() == nil {
+		t.Errorf("picture of an invalid canvas: no error")
+	}
+}
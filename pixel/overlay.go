@@ -0,0 +1,171 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"fmt"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// overlayFrames is the number of frames kept for the graph of the overlay.
+const overlayFrames = 64
+
+const (
+	overlayLayer  = 0x7FFF
+	overlayGraphH = 24  // height of the graph
+	overlayScale  = 720 // pixels per second in the graph (i.e. 1/60s is 12 pixels)
+)
+
+var overlay struct {
+	shown bool
+	times [overlayFrames][3]float32 // React, Update and Render time of each frame
+	next  int                       // current frame in times
+
+	// Statistics of the last frame
+	commands   int // draw commands (after batching)
+	primitives int
+	parameters int
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SetOverlay shows or hides the debug overlay. When shown, it is drawn on top
+// of the canvas at the end of each frame, and displays:
+//
+//   - the time spent in React, Update and Render for the last frames, as a
+//     graph with a mark at 1/60s;
+//   - the number of drawing commands executed during the frame (after
+//     batching), of the primitives they draw, and of their parameters;
+//   - the number of bins in the texture atlas, and the proportion of unused
+//     space;
+//   - the colors of the current palette.
+//
+// The overlay uses the colors of the palette nearest to black, white, red,
+// green and blue.
+func SetOverlay(show bool) {
+	overlay.shown = show
+}
+
+// Overlay returns true if the debug overlay is shown.
+func Overlay() bool {
+	return overlay.shown
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// frameStats records the statistics of the frame, and queues the drawing
+// commands of the overlay if it is shown.
+func frameStats() {
+	// The render time of the previous frame is only known now
+	overlay.times[(overlay.next+overlayFrames-1)%overlayFrames][2] = float32(internal.RenderTime)
+	overlay.times[overlay.next] = [3]float32{
+		float32(internal.ReactTime),
+		float32(internal.UpdateTime),
+		0,
+	}
+
+	overlay.commands = len(renderer.commands)
+	overlay.parameters = len(renderer.parameters)
+	overlay.primitives = 0
+	for _, c := range renderer.commands {
+		overlay.primitives += int(c.InstanceCount)
+	}
+
+	if overlay.shown {
+		drawOverlay()
+	}
+	overlay.next = (overlay.next + 1) % overlayFrames
+}
+
+func drawOverlay() {
+	setView(canvasView(Screen))
+
+	bg := NearestColor(color.SRGB{0, 0, 0})
+	fg := NearestColor(color.SRGB{1, 1, 1})
+	parts := [3]color.Index{
+		NearestColor(color.SRGB{1, 0.2, 0.2}),
+		NearestColor(color.SRGB{0.2, 1, 0.2}),
+		NearestColor(color.SRGB{0.3, 0.5, 1}),
+	}
+
+	bins, unused := int16(0), 0
+	if a := pictures.atlas; a != nil && a.BinCount() > 0 {
+		w, h := a.BinSize()
+		bins = a.BinCount()
+		unused = 100 * a.Unused() / (int(bins) * int(w) * int(h))
+	}
+	lines := []string{
+		fmt.Sprintf("frame %.1fms", internal.RenderDelta*1000),
+		fmt.Sprintf("{c:%d}react {c:%d}update {c:%d}render", parts[0], parts[1], parts[2]),
+		"", // graph
+		fmt.Sprintf("%d cmd, %d prim", overlay.commands, overlay.primitives),
+		fmt.Sprintf("%d param", overlay.parameters),
+		fmt.Sprintf("atlas %d, %d%% free", bins, unused),
+		"", // palette
+	}
+
+	c := Cursor{
+		Color:     fg,
+		Font:      Monozela10,
+		Interline: Monozela10.Height() + 1,
+		Markup:    true,
+	}
+	const pad = 2
+	o := XY{pad, pad} // top-left corner of the content
+	w := int16(overlayFrames)
+	for _, l := range lines {
+		if m := c.Measure(l).X; m > w {
+			w = m
+		}
+	}
+	rows := int16((palette.count + 15) / 16)
+	h := int16(len(lines)-2)*c.Interline + overlayGraphH + 3*rows
+	Box(fg, bg, overlayLayer-2, 0, XY{0, 0}, XY{w + 2*pad - 1, h + 2*pad - 1})
+
+	y := o.Y
+	for i, l := range lines {
+		switch i {
+		case 2:
+			overlayGraph(XY{o.X, y}, fg, parts)
+			y += overlayGraphH
+		case len(lines) - 1:
+			for j := 1; j <= palette.count; j++ {
+				p := XY{o.X + int16((j-1)%16)*3, y + int16((j-1)/16)*3}
+				Box(color.Index(j), color.Index(j), overlayLayer, 0, p, p.Plus(XY{2, 2}))
+			}
+		default:
+			c.Locate(overlayLayer, XY{o.X, y + Monozela10.Baseline()})
+			c.Print(l)
+			y += c.Interline
+		}
+	}
+}
+
+// overlayGraph draws the graph of the frame times, oldest first.
+func overlayGraph(o XY, mark color.Index, parts [3]color.Index) {
+	bottom := o.Y + overlayGraphH - 1
+	for i := 0; i < overlayFrames-1; i++ {
+		t := overlay.times[(overlay.next+1+i)%overlayFrames]
+		x := o.X + int16(i)
+		y := bottom
+		for k, p := range parts {
+			n := int16(t[k]*overlayScale + 0.5)
+			if n > y-o.Y+1 {
+				n = y - o.Y + 1
+			}
+			if n > 0 {
+				Box(p, p, overlayLayer-1, 0, XY{x, y - n + 1}, XY{x, y})
+				y -= n
+			}
+		}
+	}
+	m := bottom - overlayScale/60
+	for x := o.X; x < o.X+overlayFrames; x += 2 {
+		Point(mark, overlayLayer, XY{x, m})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOverlay(t *testing.T) {
+	defer func() {
+		internal.ReactTime, internal.UpdateTime, internal.RenderTime = 0, 0, 0
+		SetOverlay(false)
+	}()
+
+	defer headless(t, XY{200, 120})()
+
+	at := func(x, y int16) color.Index {
+		return color.Index(software.canvas.Pix[int(y)*int(screen.size.X)+int(x)])
+	}
+
+	Clear(0)
+	Box(1, 1, 0, 0, XY{150, 100}, XY{151, 100})
+	Point(1, 0, XY{150, 110})
+	Point(1, 0, XY{151, 110})
+	n := len(renderer.parameters)
+	render()
+	if overlay.commands != 2 || overlay.primitives != 3 || overlay.parameters != n {
+		t.Errorf("statistics: %d commands, %d primitives, %d parameters",
+			overlay.commands, overlay.primitives, overlay.parameters)
+	}
+	if at(0, 0) != 0 {
+		t.Errorf("overlay drawn while hidden")
+	}
+
+	SetOverlay(true)
+	internal.ReactTime, internal.UpdateTime = 1.0/120, 1.0/240
+	Clear(0)
+	render()
+	internal.ReactTime, internal.UpdateTime, internal.RenderTime = 0, 0, 1.0/360
+	Clear(0)
+	render()
+
+	white, black := NearestColor(color.SRGB{1, 1, 1}), NearestColor(color.SRGB{0, 0, 0})
+	if at(0, 0) != white || at(1, 1) != black {
+		t.Errorf("overlay frame: colors %d and %d", at(0, 0), at(1, 1))
+	}
+
+	// The previous frame is the last column of the graph
+	x := int16(2 + overlayFrames - 2)
+	y := 2 + 2*(Monozela10.Height()+1) + overlayGraphH - 1
+	want := []color.Index{
+		NearestColor(color.SRGB{1, 0.2, 0.2}),
+		NearestColor(color.SRGB{0.2, 1, 0.2}),
+		NearestColor(color.SRGB{0.3, 0.5, 1}),
+	}
+	for i, n := range []int16{6, 3, 2} {
+		for j := int16(0); j < n; j++ {
+			if c := at(x, y); c != want[i] {
+				t.Errorf("graph: color %d at %d, want %d", c, y, want[i])
+			}
+			y--
+		}
+	}
+	if at(x, y) != black || at(x+1, y) != black {
+		t.Errorf("graph: bar too high")
+	}
+}
@@ -4,6 +4,9 @@
 package pixel
 
 import (
+	stdcolor "image/color"
+	"math"
+
 	"github.com/cozely/cozely/color"
 )
 
@@ -51,6 +54,9 @@ var DefaultPalette = color.Palette{
 
 var palette struct {
 	colors [256]color.LRGBA
+	shown  [256]color.LRGBA // colors with the effects applied
+	byName map[string]color.Index
+	count  int // number of colors defined (excluding index 0)
 	dirty  bool
 }
 
@@ -66,7 +72,8 @@ var debugColor = color.LRGBA{0, 0, 0, 1}
 //
 // Note that the palette will be used for every drawing command of the current
 // frame, even those issued before the call to Use. In other words, you cannot
-// change the palette in the middle of a frame.
+// change the palette in the middle of a frame (but see CycleColors and
+// Transition for animated palettes).
 func SetPalette(p color.Palette) {
 	for c := range palette.colors {
 		switch {
@@ -78,9 +85,30 @@ func SetPalette(p color.Palette) {
 			palette.colors[c] = debugColor
 		}
 	}
+	palette.byName = p.ByName
+	palette.count = paletteCount(p)
 	palette.dirty = true
 }
 
+func paletteCount(p color.Palette) int {
+	if len(p.Colors) > 255 {
+		return 255
+	}
+	return len(p.Colors)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// stdPalette returns a copy of the displayed palette, converted for use with the
+// standard library.
+func stdPalette() stdcolor.Palette {
+	p := make(stdcolor.Palette, len(palette.shown))
+	for i, c := range palette.shown {
+		p[i] = color.SRGBA8of(c)
+	}
+	return p
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // SetColor changes the color associated with an index.
@@ -90,6 +118,9 @@ func SetColor(i color.Index, c color.Color) color.Index {
 	} else {
 		palette.colors[i] = color.LRGBAof(c)
 	}
+	if int(i) > palette.count {
+		palette.count = int(i)
+	}
 	palette.dirty = true //TODO: finer-grained palette upload
 	return color.Index(i)
 }
@@ -98,7 +129,7 @@ func SetColor(i color.Index, c color.Color) color.Index {
 
 // FindColor returns the first color index associated with specific LRGBA
 // values. If there isn't any color with these values in the palette, index 0 is
-// returned.
+// returned (see also NearestColor).
 func FindColor(v color.Color) color.Index {
 	lv := color.LRGBAof(v)
 	for c, pv := range palette.colors {
@@ -110,4 +141,26 @@ func FindColor(v color.Color) color.Index {
 	return color.Index(0)
 }
 
-//TODO: search by color proximity
+// NearestColor returns the index of the palette color closest to v (the
+// distance is measured in standard RGB space, and the first index wins in case
+// of a tie). Transparent colors (with alpha below one half) are mapped to index
+// 0.
+func NearestColor(v color.Color) color.Index {
+	l := color.LRGBAof(v)
+	if l.A < 0.5 {
+		return 0
+	}
+	// Undo the alpha premultiplication
+	l = color.LRGBA{l.R / l.A, l.G / l.A, l.B / l.A, 1}
+	r, g, b, _ := l.Standard()
+
+	best, dist := color.Index(0), float32(math.MaxFloat32)
+	for i := 1; i <= palette.count; i++ {
+		pr, pg, pb, _ := palette.colors[i].Standard()
+		d := (r-pr)*(r-pr) + (g-pg)*(g-pg) + (b-pb)*(b-pb)
+		if d < dist {
+			best, dist = color.Index(i), d
+		}
+	}
+	return best
+}
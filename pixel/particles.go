@@ -0,0 +1,181 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/coord"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// An Emitter spawns and animates particles, e.g. dust, sparks or explosions.
+//
+// The particles are simulated with the fixed time step of the framework: call
+// Update from the Update method of the game loop, and Paint from the Render
+// method; the positions are then interpolated with UpdateLag. All particles of
+// an emitter are queued with a single GPU command.
+//
+// The parameters can be changed at any time; they only affect the particles
+// spawned afterward. Ranges are given as minimum and maximum, and each particle
+// picks a random value in between.
+type Emitter struct {
+	Position coord.XY // center of the spawn area
+	Extent   coord.XY // half-size of the spawn area ({0, 0} for a point)
+	Rate     float32  // particles per second (0 for bursts only)
+	Max      int      // maximum number of live particles (0 for no limit)
+
+	MinLife, MaxLife   float32 // in seconds
+	MinSpeed, MaxSpeed float32 // in pixels per second
+	// Direction of the initial velocity, in radians, counter-clockwise from 3
+	// o'clock; the particles are emitted in a cone of width Spread around it.
+	Direction, Spread float32
+	Gravity           coord.XY // acceleration, in pixels per second squared
+
+	// Ramp is the succession of colors taken by each particle over its life
+	// (the first one at birth, the last one just before death).
+	Ramp []color.Index
+	// If not empty, the particles are painted with these pictures instead of
+	// points, centered on their position. Like the ramp, the pictures are
+	// chosen according to the age of the particle.
+	Pictures []PictureID
+
+	particles []particle
+	pending   float32 // fraction of particle left from the last update
+}
+
+type particle struct {
+	position, previous coord.XY
+	velocity           coord.XY
+	age, life          float32
+}
+
+var particleRand = rand.New(rand.NewSource(1))
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Burst spawns n particles immediately.
+func (e *Emitter) Burst(n int) {
+	for i := 0; i < n; i++ {
+		if e.Max > 0 && len(e.particles) >= e.Max {
+			return
+		}
+		e.spawn()
+	}
+}
+
+func (e *Emitter) spawn() {
+	p := particle{
+		position: coord.XY{
+			X: e.Position.X + e.Extent.X*(2*particleRand.Float32()-1),
+			Y: e.Position.Y + e.Extent.Y*(2*particleRand.Float32()-1),
+		},
+		life: between(e.MinLife, e.MaxLife),
+	}
+	p.previous = p.position
+
+	a := float64(e.Direction + e.Spread*(particleRand.Float32()-0.5))
+	s := between(e.MinSpeed, e.MaxSpeed)
+	p.velocity = coord.XY{
+		X: s * float32(math.Cos(a)),
+		Y: -s * float32(math.Sin(a)),
+	}
+
+	e.particles = append(e.particles, p)
+}
+
+func between(min, max float32) float32 {
+	if max <= min {
+		return min
+	}
+	return min + (max-min)*particleRand.Float32()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Update spawns new particles according to the rate, moves all particles by one
+// time step, and removes those that reached the end of their life.
+func (e *Emitter) Update() {
+	dt := float32(internal.UpdateStep)
+
+	live := e.particles[:0]
+	for _, p := range e.particles {
+		p.age += dt
+		if p.age >= p.life {
+			continue
+		}
+		p.previous = p.position
+		p.velocity = p.velocity.Plus(e.Gravity.Times(dt))
+		p.position = p.position.Plus(p.velocity.Times(dt))
+		live = append(live, p)
+	}
+	e.particles = live
+
+	e.pending += e.Rate * dt
+	n := int(e.pending)
+	e.pending -= float32(n)
+	e.Burst(n)
+}
+
+// Count returns the number of live particles.
+func (e *Emitter) Count() int {
+	return len(e.particles)
+}
+
+// Clear removes all particles.
+func (e *Emitter) Clear() {
+	e.particles = e.particles[:0]
+	e.pending = 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Paint queues a GPU command to draw all the particles, at their position
+// interpolated between the last two updates.
+func (e *Emitter) Paint(layer int16) {
+	if len(e.particles) == 0 {
+		return
+	}
+
+	t := float32(internal.UpdateLag / internal.UpdateStep)
+	prm := make([]int16, 0, 4*len(e.particles)) //TODO: remove alloc
+
+	if len(e.Pictures) > 0 {
+		for _, p := range e.particles {
+			pict := e.Pictures[over(p, len(e.Pictures))]
+			pos := RoundXYof(p.previous.Plus(p.position.Minus(p.previous).Times(t)))
+			pos = pos.Minus(pict.Size().Slash(2))
+			prm = append(prm, int16(pict), layer, pos.X, pos.Y)
+		}
+		renderer.command(cmdPicture, 4, uint32(len(e.particles)), prm...)
+		return
+	}
+
+	if len(e.Ramp) == 0 {
+		return
+	}
+	for _, p := range e.particles {
+		c := e.Ramp[over(p, len(e.Ramp))]
+		pos := RoundXYof(p.previous.Plus(p.position.Minus(p.previous).Times(t)))
+		prm = append(prm, int16(c), layer, pos.X, pos.Y)
+	}
+	renderer.command(cmdPoint, 3, uint32(len(e.particles)), prm...)
+}
+
+// over returns the index, in a sequence of n items, corresponding to the age of
+// a particle.
+func over(p particle, n int) int {
+	if p.life <= 0 {
+		return n - 1
+	}
+	i := int(p.age / p.life * float32(n))
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"image"
+	"testing"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/coord"
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParticles(t *testing.T) {
+	step := internal.UpdateStep
+	defer func() {
+		internal.UpdateStep = step
+		internal.UpdateLag = 0
+	}()
+
+	m := image.NewPaletted(image.Rect(0, 0, 1, 1), nil)
+	m.Pix = []uint8{7}
+	pict := PictureImage(m)
+
+	defer headless(t, XY{6, 3})()
+
+	internal.UpdateStep = 0.5
+	e := Emitter{
+		Position: coord.XY{1, 1},
+		Rate:     2,
+		MinLife:  2,
+		MinSpeed: 4,
+		Ramp:     []color.Index{1, 2, 3, 4},
+	}
+
+	e.Update()
+	e.Update()
+	if e.Count() != 2 {
+		t.Fatalf("want 2 particles, got %d", e.Count())
+	}
+
+	internal.UpdateLag = 0.25
+	Clear(0)
+	e.Paint(0)
+	render()
+	if got := dump(software.canvas.Pix, 6); got != "000000\n012000\n000000" {
+		t.Errorf("interpolated particles:\n%s", got)
+	}
+
+	// The newest particle is painted over the second one
+	internal.UpdateLag = 0
+	e.Update()
+	Clear(0)
+	e.Paint(0)
+	render()
+	if got := dump(software.canvas.Pix, 6); got != "000000\n010300\n000000" {
+		t.Errorf("particles after update:\n%s", got)
+	}
+
+	// Lifetime and limit
+
+	e.Rate = 0
+	for i := 0; i < 4; i++ {
+		e.Update()
+	}
+	if e.Count() != 0 {
+		t.Errorf("want no particle at end of life, got %d", e.Count())
+	}
+	e.Max = 3
+	e.Burst(5)
+	if e.Count() != 3 {
+		t.Errorf("want 3 particles, got %d", e.Count())
+	}
+	e.Clear()
+
+	// Gravity and pictures
+
+	e = Emitter{
+		Position: coord.XY{1, 0},
+		MinLife:  10,
+		Gravity:  coord.XY{0, 8},
+		Pictures: []PictureID{pict},
+	}
+	e.Burst(1)
+	e.Update()
+	e.Update()
+	Clear(0)
+	e.Paint(0)
+	render()
+	if got := dump(software.canvas.Pix, 6); got != "000000\n000000\n070000" {
+		t.Errorf("picture particles:\n%s", got)
+	}
+}
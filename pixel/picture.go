@@ -6,8 +6,9 @@ package pixel
 import (
 	"errors"
 	"image"
-	"os"
-	"path/filepath"
+	stdcolor "image/color"
+	"io"
+	"io/fs"
 
 	"github.com/cozely/cozely/internal"
 	"github.com/cozely/cozely/x/atlas"
@@ -29,10 +30,16 @@ const MouseCursor = PictureID(1)
 var pictures = struct {
 	atlas   *atlas.Atlas
 	path    []string
+	fsys    []fs.FS
+	colors  []ColorMapping // color mapping at declaration
 	mapping []mapping
 	image   []*image.Paletted
+	pending []uint32 // pictures packed but not yet uploaded
+	dirty   bool     // mapping changed since last upload
 }{
 	path:    []string{"", ""},
+	fsys:    []fs.FS{nil, nil},
+	colors:  []ColorMapping{KeepIndices, KeepIndices},
 	mapping: []mapping{{}, {}},
 	image:   []*image.Paletted{nil, nil},
 }
@@ -45,40 +52,147 @@ type mapping struct {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-// Picture declares a new picture and returns its ID.
+// Picture declares a new picture and returns its ID. The picture is loaded from
+// the PNG file "<path>.png", relative to the application path. Its colors are
+// converted to palette indices according to the color mapping in effect at
+// declaration (see SetColorMapping).
+//
+// If the path ends with ".aseprite" or ".ase", the picture is instead the first
+// frame of this Aseprite file, with all visible layers flattened.
+//
+// Pictures declared before starting the framework are all loaded at once.
+// Pictures declared while the framework is running are loaded immediately
+// (see also Unload).
 func Picture(path string) PictureID {
-	if internal.Running {
-		setErr(errors.New("pixel picture declaration: declarations must happen before starting the framework"))
+	return newPicture(nil, path, nil)
+}
+
+// PictureFS declares a new picture loaded from the PNG file "<path>.png" inside
+// fsys (for example an embed.FS). It is otherwise identical to Picture.
+func PictureFS(fsys fs.FS, path string) PictureID {
+	if fsys == nil {
+		setErr(errors.New("pixel picture declaration: nil file system"))
 		return noPicture
 	}
+	return newPicture(fsys, path, nil)
+}
 
-	if len(pictures.mapping) >= maxPictureID {
-		setErr(errors.New("pixel picture declaration: too many pictures"))
+// PictureImage declares a new picture from an image. The image is kept by the
+// package: after modifying it, call Reload to update the picture.
+func PictureImage(img *image.Paletted) PictureID {
+	if img == nil {
+		setErr(errors.New("pixel picture declaration: nil image"))
 		return noPicture
 	}
+	return newPicture(nil, "", img)
+}
 
-	pictures.path = append(pictures.path, path)
-	pictures.image = append(pictures.image, nil)
-	pictures.mapping = append(pictures.mapping, mapping{})
-	return PictureID(len(pictures.path) - 1)
+// PictureReader declares a new picture from the content of r, which is decoded
+// immediately (the reader is not used afterward). The colors are mapped with
+// the current palette and color mapping (see SetColorMapping).
+func PictureReader(r io.Reader) PictureID {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		setErr(internal.Wrap("pixel picture declaration: decoding image", err))
+		return noPicture
+	}
+
+	return newPicture(nil, "", indexed(img, colorMapping))
 }
 
 // picture declares a new picture (from an image) and returns its ID.
 func picture(img *image.Paletted) PictureID {
-	if internal.Running {
-		setErr(errors.New("pixel picture declaration: declarations must happen before starting the framework"))
-		return noPicture
-	}
+	return newPicture(nil, "", img)
+}
 
+func newPicture(fsys fs.FS, path string, img *image.Paletted) PictureID {
 	if len(pictures.mapping) >= maxPictureID {
 		setErr(errors.New("pixel picture declaration: too many pictures"))
 		return noPicture
 	}
 
-	pictures.path = append(pictures.path, "")
+	pictures.path = append(pictures.path, path)
+	pictures.fsys = append(pictures.fsys, fsys)
+	pictures.colors = append(pictures.colors, colorMapping)
 	pictures.image = append(pictures.image, img)
 	pictures.mapping = append(pictures.mapping, mapping{})
-	return PictureID(len(pictures.path) - 1)
+	p := PictureID(len(pictures.path) - 1)
+
+	if internal.Running {
+		p.Reload()
+	}
+	return p
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Unload frees the space used by the picture in the texture atlas. The ID stays
+// valid: painting an unloaded picture draws nothing, until it is loaded again
+// with Reload.
+//
+// This can only be done while the framework is running.
+func (p PictureID) Unload() {
+	if !internal.Running {
+		setErr(errors.New("pixel picture unload: the framework is not running"))
+		return
+	}
+	if p <= MouseCursor || int(p) >= len(pictures.mapping) {
+		setErr(errors.New("pixel picture unload: invalid picture ID"))
+		return
+	}
+
+	p.unload()
+}
+
+func (p PictureID) unload() {
+	m := &pictures.mapping[p]
+	if m.w == 0 || m.h == 0 {
+		return
+	}
+
+	pictures.atlas.Remove(m.bin, uint32(p))
+	*m = mapping{}
+	if pictures.path[p] != "" {
+		pictures.image[p] = nil
+	}
+	pictures.dirty = true
+}
+
+// Reload loads the picture again, after an Unload. If the picture is already
+// loaded, it is replaced by the current content of its file (this can be used
+// to reload edited assets).
+//
+// This can only be done while the framework is running.
+func (p PictureID) Reload() {
+	if !internal.Running {
+		setErr(errors.New("pixel picture reload: the framework is not running"))
+		return
+	}
+	if p <= MouseCursor || int(p) >= len(pictures.mapping) {
+		setErr(errors.New("pixel picture reload: invalid picture ID"))
+		return
+	}
+
+	p.unload()
+	if pictures.path[p] == "" && pictures.image[p] == nil {
+		setErr(errors.New("pixel picture reload: no image to load"))
+		return
+	}
+
+	prects := []uint32{}
+	err := p.load(&prects)
+	if err != nil {
+		setErr(internal.Wrap("pixel picture reload", err))
+		return
+	}
+	pack(prects)
+}
+
+// IsLoaded returns true if the picture currently occupies space in the texture
+// atlas.
+func (p PictureID) IsLoaded() bool {
+	m := pictures.mapping[p]
+	return m.w > 0 && m.h > 0
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -115,32 +229,9 @@ func (p PictureID) load(prects *[]uint32) error {
 		return nil
 	}
 
-	n := pictures.path[p]
-	//TODO: support other image formats?
-	path := filepath.FromSlash(internal.Path + n + ".png")
-	path, err := filepath.EvalSymlinks(path)
-	if err != nil {
-		return internal.Wrap("in path while scanning picture", err)
-	}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return internal.Wrap(`while opening image "`+path+`"`, err)
-	}
-	defer f.Close() //TODO: error handling
-
-	img, _, err := image.Decode(f)
-	switch err {
-	case nil:
-	case image.ErrFormat:
-		return nil
-	default:
-		return internal.Wrap("decoding picture file", err)
-	}
-
-	m, ok := img.(*image.Paletted)
-	if !ok {
-		return errors.New("impossible to load picture " + path + " (color model not supported)")
+	m, err := p.decode()
+	if err != nil || m == nil {
+		return err
 	}
 
 	//TODO: check for width and height overflow
@@ -153,6 +244,38 @@ func (p PictureID) load(prects *[]uint32) error {
 	return nil
 }
 
+// decode reads the file of a picture, and returns its image converted to the
+// palette (or nil if the file format is unknown).
+func (p PictureID) decode() (*image.Paletted, error) {
+	var img image.Image
+	if isAseprite(pictures.path[p]) {
+		f, err := readAseprite(pictures.fsys[p], pictures.path[p])
+		if err != nil {
+			return nil, err
+		}
+		img = f.Image(0)
+	} else {
+		//TODO: support other image formats?
+		path := pictures.path[p] + ".png"
+		f, err := open(pictures.fsys[p], path)
+		if err != nil {
+			return nil, internal.Wrap(`while opening image "`+path+`"`, err)
+		}
+		defer f.Close() //TODO: error handling
+
+		img, _, err = image.Decode(f)
+		switch err {
+		case nil:
+		case image.ErrFormat:
+			return nil, nil
+		default:
+			return nil, internal.Wrap("decoding picture file", err)
+		}
+	}
+
+	return indexed(img, pictures.colors[p]), nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 func pictSize(rect uint32) (width, height int16) {
@@ -184,3 +307,56 @@ func pictPaint(rect uint32, dest interface{}) error {
 
 	return nil
 }
+
+// pack adds pictures to the texture atlas (extending it if necessary), and
+// schedules their upload to the renderer.
+func pack(prects []uint32) {
+	pictures.atlas.Pack(prects, pictSize, pictPut)
+	pictures.pending = append(pictures.pending, prects...)
+	pictures.dirty = true
+}
+
+// uploaded releases the images of all pictures that have been uploaded to the
+// renderer, except those that cannot be loaded again from a file.
+func uploaded() {
+	for _, r := range pictures.pending {
+		if pictures.path[r] != "" {
+			pictures.image[r] = nil
+		}
+	}
+	pictures.pending = pictures.pending[:0]
+	pictures.dirty = false
+}
+
+// packed returns an image whose stride is equal to its width (as expected by
+// texture uploads).
+func packed(m *image.Paletted) *image.Paletted {
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	if m.Stride == w {
+		return m
+	}
+	n := image.NewPaletted(image.Rect(0, 0, w, h), m.Palette)
+	for y := 0; y < h; y++ {
+		copy(n.Pix[y*w:(y+1)*w], m.Pix[y*m.Stride:])
+	}
+	return n
+}
+
+// paintBin returns an image of one of the bins of the atlas, with all the
+// pictures mapped to it.
+func paintBin(bin int16) (*image.Paletted, error) {
+	w, h := pictures.atlas.BinSize()
+	m := image.NewPaletted(image.Rectangle{
+		Min: image.Point{0, 0},
+		Max: image.Point{int(w), int(h)},
+	},
+		stdcolor.Palette{},
+	)
+
+	err := pictures.atlas.Paint(bin, m, pictPaint)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
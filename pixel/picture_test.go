@@ -0,0 +1,42 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestPictureSources(t *testing.T) {
+	m := paletted(2, 1, 2, 3)
+	f := pngFile(t, m)
+
+	pp := []PictureID{
+		PictureFS(fstest.MapFS{"graphics/a.png": f}, "graphics/a"),
+		PictureReader(bytes.NewReader(f.Data)),
+		PictureImage(m),
+	}
+
+	defer headless(t, XY{6, 1})()
+
+	Clear(1)
+	for i, p := range pp {
+		if p.Size() != (XY{2, 1}) {
+			t.Errorf("picture %d: want size %v, got %v", i, XY{2, 1}, p.Size())
+		}
+		p.Paint(0, XY{int16(2 * i), 0})
+	}
+	render()
+
+	if got, want := software.canvas.Pix, []uint8{2, 3, 2, 3, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pictures: want %v, got %v", want, got)
+	}
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// RemapID is the ID of a color remapping table, used to change the colors of a
+// picture when painting it (see PictureID.PaintExt). This allows the same
+// picture to be displayed with different color ramps, e.g. for team colors.
+type RemapID uint8
+
+const maxRemapID = 0xFF
+
+// NoRemap is the identity table (all colors are kept as is).
+const NoRemap = RemapID(0)
+
+var remaps = struct {
+	tables [][256]color.Index
+	dirty  bool
+}{
+	tables: [][256]color.Index{identityRemap()},
+	dirty:  true,
+}
+
+func identityRemap() [256]color.Index {
+	var t [256]color.Index
+	for i := range t {
+		t[i] = color.Index(i)
+	}
+	return t
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Remap declares a new remapping table: each color index found in m is
+// replaced by the associated index; other colors are kept. Tables can be
+// declared at any time.
+func Remap(m map[color.Index]color.Index) RemapID {
+	t := identityRemap()
+	for k, v := range m {
+		t[k] = v
+	}
+	return newRemap(t)
+}
+
+// RemapRange declares a new remapping table that replaces the color indices from
+// first to last (included) by the same number of indices, starting at to. It
+// can be used to swap color ramps.
+func RemapRange(first, last, to color.Index) RemapID {
+	if last < first || int(to)+int(last-first) > 0xFF {
+		setErr(errors.New("pixel remap declaration: invalid range"))
+		return NoRemap
+	}
+	t := identityRemap()
+	for i := int(first); i <= int(last); i++ {
+		t[i] = to + color.Index(i-int(first))
+	}
+	return newRemap(t)
+}
+
+func newRemap(t [256]color.Index) RemapID {
+	if len(remaps.tables) > maxRemapID {
+		setErr(errors.New("pixel remap declaration: too many tables"))
+		return NoRemap
+	}
+	t[0] = 0 // Transparency is never remapped
+	remaps.tables = append(remaps.tables, t)
+	remaps.dirty = true
+	return RemapID(len(remaps.tables) - 1)
+}
+
+// Set changes a single entry of a remapping table.
+func (r RemapID) Set(from, to color.Index) {
+	if r == NoRemap || int(r) >= len(remaps.tables) || from == 0 {
+		setErr(errors.New("pixel remap: invalid change"))
+		return
+	}
+	remaps.tables[r][from] = to
+	remaps.dirty = true
+}
+
+// Color returns the color index that replaces c in the table.
+func (r RemapID) Color(c color.Index) color.Index {
+	return remaps.tables[r][c]
+}
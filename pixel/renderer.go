@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"github.com/cozely/cozely/internal"
+	"github.com/cozely/cozely/x/atlas"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// atlasSize is the size of the bins of the texture atlas, i.e. the maximum size
+// of a picture.
+const atlasSize = 1024
+
+// The commands are always queued by the GPU renderer; when the framework is
+// headless, they are executed by the software renderer instead.
+
+func init() {
+	internal.PixelSetup = setup
+	internal.PixelCleanup = cleanup
+	internal.PixelRender = render
+}
+
+func setup() error {
+	// Create texture atlas for pictures (and fonts glyphs)
+
+	pictures.atlas = atlas.New(atlasSize, atlasSize)
+
+	err := loadAssets()
+	if err != nil {
+		return err
+	}
+
+	if internal.Config.Headless {
+		err = software.setup()
+	} else {
+		err = renderer.setup()
+	}
+
+	// The pictures are now in the renderer
+	uploaded()
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func cleanup() error {
+	// Palette
+	SetPalette(DefaultPalette)
+	palette.dirty = true
+	effects.cycles = effects.cycles[:0]
+	effects.fading = false
+
+	var err error
+	if internal.Config.Headless {
+		err = software.cleanup()
+	} else {
+		err = renderer.cleanup()
+	}
+
+	// Pictures
+	pictures.atlas = nil
+	pictures.path = pictures.path[:2]
+	pictures.fsys = pictures.fsys[:2]
+	pictures.colors = pictures.colors[:2]
+	pictures.image = pictures.image[:2]
+	pictures.mapping = pictures.mapping[:2]
+	pictures.pending = pictures.pending[:0]
+	pictures.dirty = false
+
+	// Fonts
+	fonts = fonts[:1]
+	fontPaths = fontPaths[:1]
+
+	// Remapping tables
+	remaps.tables = remaps.tables[:1]
+	remaps.dirty = true
+
+	// Cameras
+	renderer.views = renderer.views[:0]
+
+	// Off-screen canvases
+	canvases.size = canvases.size[:1]
+	canvases.picture = canvases.picture[:1]
+	canvases.clear = canvases.clear[:1]
+
+	// Sprite sheets
+	sheets = sheets[:0]
+	sheetPaths = sheetPaths[:0]
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func adjustScreenTextures() {
+	if internal.Config.Headless {
+		software.adjust()
+		return
+	}
+	renderer.adjust()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// render executes all pending commands on the canvas. It is automatically called
+// by Display; the only reason to call it manually is to be able to read from it
+// before display.
+func render() error {
+	if screen.dirty && internal.Running {
+		// Resolution or zoom changed since last frame
+		resize()
+	}
+
+	frameStats()
+
+	updatePalette()
+
+	if pictures.dirty {
+		// Pictures have been loaded or unloaded since last frame
+		if internal.Config.Headless {
+			software.update()
+		} else {
+			renderer.update()
+		}
+		uploaded()
+	}
+
+	if internal.Config.Headless {
+		return software.render()
+	}
+	return renderer.render()
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"math"
+	"sort"
+
+	"github.com/cozely/cozely/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// The shapes are rasterized on the CPU, and queued as horizontal spans (i.e.
+// boxes one pixel high), so that they are pixel-exact and identical on all
+// renderers. Like the other primitives, they are depth-tested with their
+// layer.
+
+// Circle queues the GPU commands to draw the outline of a circle. The outline
+// is exactly the border of the disc drawn by FillCircle.
+func Circle(c color.Index, layer int16, center XY, radius int16) {
+	Ellipse(c, layer, center, XY{radius, radius})
+}
+
+// FillCircle queues the GPU commands to draw a disc. It contains the pixels
+// whose center is within radius+1/2 of the center.
+func FillCircle(c color.Index, layer int16, center XY, radius int16) {
+	FillEllipse(c, layer, center, XY{radius, radius})
+}
+
+// Ellipse queues the GPU commands to draw the outline of an axis-aligned
+// ellipse, with horizontal and vertical radii.
+func Ellipse(c color.Index, layer int16, center XY, radii XY) {
+	if radii.X < 0 || radii.Y < 0 {
+		return
+	}
+	hw := halfWidths(radii)
+	for j := range hw {
+		y := center.Y + int16(j) - radii.Y
+		l := outline(hw, j)
+		if l < 0 {
+			span(c, layer, y, center.X-hw[j], center.X+hw[j])
+			continue
+		}
+		span(c, layer, y, center.X-hw[j], center.X-l-1)
+		span(c, layer, y, center.X+l+1, center.X+hw[j])
+	}
+}
+
+// FillEllipse queues the GPU commands to draw a filled axis-aligned ellipse,
+// with horizontal and vertical radii.
+func FillEllipse(c color.Index, layer int16, center XY, radii XY) {
+	if radii.X < 0 || radii.Y < 0 {
+		return
+	}
+	hw := halfWidths(radii)
+	for j := range hw {
+		y := center.Y + int16(j) - radii.Y
+		span(c, layer, y, center.X-hw[j], center.X+hw[j])
+	}
+}
+
+// Arc queues the GPU commands to draw a part of the outline of a circle. The
+// angles are in radians, counter-clockwise from 3 o'clock; the arc goes
+// counter-clockwise from the first angle to the second.
+func Arc(c color.Index, layer int16, center XY, radius int16, from, to float32) {
+	if radius < 0 {
+		return
+	}
+	hw := halfWidths(XY{radius, radius})
+	for j := range hw {
+		dy := int16(j) - radius
+		l := outline(hw, j)
+		runs(c, layer, center, dy, -hw[j], hw[j], func(dx int16) bool {
+			return (dx < -l || dx > l) && within(dx, dy, from, to)
+		})
+	}
+}
+
+// FillArc queues the GPU commands to draw a pie slice, i.e. the part of the
+// disc drawn by FillCircle between two angles (see Arc).
+func FillArc(c color.Index, layer int16, center XY, radius int16, from, to float32) {
+	if radius < 0 {
+		return
+	}
+	hw := halfWidths(XY{radius, radius})
+	for j := range hw {
+		dy := int16(j) - radius
+		runs(c, layer, center, dy, -hw[j], hw[j], func(dx int16) bool {
+			return within(dx, dy, from, to)
+		})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Polygon queues a GPU command to draw the outline of a closed polygon.
+func Polygon(c color.Index, layer int16, vertices ...XY) {
+	if len(vertices) < 2 {
+		return
+	}
+	Lines(c, layer, append(vertices[:len(vertices):len(vertices)], vertices[0])...)
+}
+
+// FillPolygon queues the GPU commands to draw a filled polygon, which can be
+// concave or self-intersecting (the inside is defined by the even-odd rule).
+// Like with Triangles, the pixels on the left and top edges are included, but
+// not those on the right and bottom edges.
+func FillPolygon(c color.Index, layer int16, vertices ...XY) {
+	if len(vertices) < 3 {
+		return
+	}
+	top, bottom := vertices[0].Y, vertices[0].Y
+	for _, v := range vertices {
+		if v.Y < top {
+			top = v.Y
+		}
+		if v.Y > bottom {
+			bottom = v.Y
+		}
+	}
+
+	xs := []int{}
+	for y := int(top); y < int(bottom); y++ {
+		xs = xs[:0]
+		for i, a := range vertices {
+			b := vertices[(i+1)%len(vertices)]
+			y1, y2 := int(a.Y), int(b.Y)
+			if (y1 <= y) == (y2 <= y) {
+				continue
+			}
+			// First pixel at or after the crossing
+			n, d := int(a.X)*(y2-y1)+(y-y1)*(int(b.X)-int(a.X)), y2-y1
+			xs = append(xs, ceilDiv(n, d))
+		}
+		sort.Ints(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			span(c, layer, int16(y), int16(xs[i]), int16(xs[i+1]-1))
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ThickLines queues the GPU commands to draw a line strip of any width; the
+// ends and joints are rounded. With a width of 1, this is the same as Lines.
+func ThickLines(c color.Index, layer int16, width int16, strip ...XY) {
+	if width <= 1 {
+		Lines(c, layer, strip...)
+		return
+	}
+	if len(strip) < 2 {
+		return
+	}
+
+	// Pixels are included if their center is within half the width of the
+	// segment; for even widths, the line is shifted by half a pixel toward the
+	// top-left, so that it is exactly width pixels across.
+	rr := float64(width) * float64(width) / 4
+	o := 0.5 * float64(1-width%2)
+	e := (width + 1) / 2
+
+	for i := 0; i+1 < len(strip); i++ {
+		a, b := strip[i], strip[i+1]
+		ax, ay := float64(a.X)-o, float64(a.Y)-o
+		dx, dy := float64(b.X)-float64(a.X), float64(b.Y)-float64(a.Y)
+		ll := dx*dx + dy*dy
+
+		inside := func(x, y int16) bool {
+			px, py := float64(x)-ax, float64(y)-ay
+			t := 0.0
+			if ll > 0 {
+				t = math.Max(0, math.Min(1, (px*dx+py*dy)/ll))
+			}
+			px, py = px-t*dx, py-t*dy
+			return px*px+py*py <= rr
+		}
+
+		x1, x2 := min16(a.X, b.X)-e, max16(a.X, b.X)+e
+		for y := min16(a.Y, b.Y) - e; y <= max16(a.Y, b.Y)+e; y++ {
+			runs(c, layer, XY{}, y, x1, x2, func(x int16) bool {
+				return inside(x, y)
+			})
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// halfWidths returns, for each row of an ellipse (from top to bottom), the
+// number of pixels on each side of the center. A pixel is inside if its center
+// is within the ellipse of radii+1/2.
+func halfWidths(radii XY) []int16 {
+	a, b := float64(radii.X)+0.5, float64(radii.Y)+0.5
+	hw := make([]int16, 2*radii.Y+1)
+	for j := range hw {
+		y := float64(j) - float64(radii.Y)
+		hw[j] = int16(math.Floor(a * math.Sqrt(1-(y*y)/(b*b))))
+	}
+	return hw
+}
+
+// outline returns, for a row of an ellipse, the half-width of the inner part
+// that is not on the border (or -1 if the whole row is on the border). A pixel
+// is on the border if one of its 4 neighbours is outside.
+func outline(hw []int16, j int) int16 {
+	if j == 0 || j == len(hw)-1 {
+		return -1
+	}
+	inner := hw[j] - 1
+	if hw[j-1] < inner {
+		inner = hw[j-1]
+	}
+	if hw[j+1] < inner {
+		inner = hw[j+1]
+	}
+	return inner
+}
+
+// within returns true if the direction of (dx, dy) (on the canvas, where Y is
+// pointing down) is between the two angles.
+func within(dx, dy int16, from, to float32) bool {
+	if dx == 0 && dy == 0 {
+		return true
+	}
+	// Note: the angle is rounded to single precision, so that the angles
+	// given as parameters match the pixels on the boundary.
+	a := float64(float32(math.Atan2(-float64(dy), float64(dx))))
+	f, t := float64(from), float64(to)
+	t = math.Mod(t-f, 2*math.Pi)
+	if t < 0 {
+		t += 2 * math.Pi
+	}
+	if t == 0 && from != to {
+		return true
+	}
+	a = math.Mod(a-f, 2*math.Pi)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a <= t
+}
+
+// runs queues a span for each run of consecutive pixels of a row that satisfy
+// a predicate. The coordinates are relative to the origin o.
+func runs(c color.Index, layer int16, o XY, y, x1, x2 int16, inside func(x int16) bool) {
+	s := false
+	var start int16
+	for x := x1; x <= x2; x++ {
+		in := inside(x)
+		switch {
+		case in && !s:
+			start = x
+		case !in && s:
+			span(c, layer, o.Y+y, o.X+start, o.X+x-1)
+		}
+		s = in
+	}
+	if s {
+		span(c, layer, o.Y+y, o.X+start, o.X+x2)
+	}
+}
+
+// span queues a horizontal line, with both ends included (nothing is drawn if
+// x2 < x1).
+func span(c color.Index, layer int16, y, x1, x2 int16) {
+	if x2 < x1 {
+		return
+	}
+	renderer.command(cmdBox, 4, 1,
+		int16(uint32(c)<<8|uint32(c)),
+		layer,
+		0,
+		x1, y,
+		x2, y)
+}
+
+func ceilDiv(n, d int) int {
+	if d < 0 {
+		n, d = -n, -d
+	}
+	q := n / d
+	if n%d != 0 && n > 0 {
+		q++
+	}
+	return q
+}
+
+func min16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
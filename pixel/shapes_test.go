@@ -0,0 +1,49 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"math"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestShapes(t *testing.T) {
+	defer headless(t, XY{9, 7})()
+
+	for _, c := range []struct {
+		name string
+		draw func()
+		want string
+	}{
+		{"Circle", func() { Circle(1, 0, XY{4, 3}, 2) },
+			"000000000\n000111000\n001000100\n001000100\n001000100\n000111000\n000000000"},
+		{"FillCircle", func() { FillCircle(1, 0, XY{4, 3}, 3) },
+			"000111000\n001111100\n011111110\n011111110\n011111110\n001111100\n000111000"},
+		{"Ellipse", func() { Ellipse(1, 0, XY{4, 3}, XY{4, 2}) },
+			"000000000\n001111100\n110000011\n100000001\n110000011\n001111100\n000000000"},
+		{"FillEllipse", func() { FillEllipse(1, 0, XY{4, 3}, XY{4, 1}) },
+			"000000000\n000000000\n011111110\n111111111\n011111110\n000000000\n000000000"},
+		{"Arc", func() { Arc(1, 0, XY{4, 3}, 3, 0, math.Pi/2) },
+			"000011000\n000000100\n000000010\n000000010\n000000000\n000000000\n000000000"},
+		{"FillArc", func() { FillArc(1, 0, XY{4, 3}, 3, math.Pi/2, 2*math.Pi) },
+			"000110000\n001110000\n011110000\n011111110\n011111110\n001111100\n000111000"},
+		{"Polygon", func() { Polygon(1, 0, XY{1, 1}, XY{7, 1}, XY{4, 5}) },
+			"000000000\n011111110\n001000100\n001001000\n000101000\n000010000\n000000000"},
+		{"FillPolygon", func() { FillPolygon(1, 0, XY{0, 0}, XY{8, 0}, XY{8, 6}, XY{4, 2}, XY{0, 6}) },
+			"111111110\n111111110\n111111110\n111001110\n110000110\n100000010\n000000000"},
+		{"ThickLines", func() { ThickLines(1, 0, 3, XY{1, 1}, XY{6, 1}, XY{6, 5}) },
+			"111111110\n111111110\n111111110\n000001110\n000001110\n000001110\n000001110"},
+		{"ThickLines even", func() { ThickLines(1, 0, 2, XY{1, 3}, XY{7, 3}) },
+			"000000000\n000000000\n111111110\n111111110\n000000000\n000000000\n000000000"},
+	} {
+		Clear(0)
+		c.draw()
+		render()
+		if got := dump(software.canvas.Pix, 9); got != c.want {
+			t.Errorf("%s:\nwant:\n%s\ngot:\n%s", c.name, c.want, got)
+		}
+	}
+}
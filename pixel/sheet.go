@@ -0,0 +1,273 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"io/fs"
+
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SheetID is the ID to handle sprite sheets, i.e. images cut into several
+// frames.
+type SheetID uint16
+
+const (
+	maxSheetID = 0xFFFF
+	noSheet    = SheetID(maxSheetID)
+)
+
+var sheetPaths []string
+
+var sheets []sheet
+
+type sheet struct {
+	fsys      fs.FS
+	colors    ColorMapping        // color mapping at declaration
+	size      XY                  // size of the frames (for grid sheets)
+	first     uint16              // picture of the first frame
+	count     uint16              // number of frames
+	durations []float64           // duration of each frame (nil for grids)
+	names     map[string]uint16   // frame of each name
+	tags      map[string]sheetTag // frame range of each tag
+	centers   map[string][2]XY    // nine-slice center of each slice (Aseprite)
+	loaded    bool
+}
+
+type sheetTag struct {
+	from, to int16
+	mode     LoopMode
+	reverse  bool
+}
+
+// sheetDescription is the content of the JSON file accompanying a sheet image.
+// It follows the "array" layout exported by Aseprite.
+type sheetDescription struct {
+	Frames []struct {
+		Filename string
+		Frame    struct{ X, Y, W, H int }
+		Duration int // in milliseconds
+	}
+	Meta struct {
+		FrameTags []struct {
+			Name      string
+			From, To  int
+			Direction string // "forward", "reverse" or "pingpong"
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Sheet declares a new sprite sheet and returns its ID. The sheet is loaded
+// from the PNG file "<path>.png", and each of its frames becomes a picture.
+// Its colors are converted to palette indices according to the color mapping
+// in effect at declaration (see SetColorMapping).
+//
+// If frame is not zero, the image is cut into a grid of frames of this size,
+// numbered from left to right and top to bottom. Otherwise, the frames are
+// described in the JSON file "<path>.json", in the format exported by Aseprite
+// (with the "Array" option): each frame has a rectangle in the image, a
+// duration and a name, and the tags define the frame ranges of animations.
+//
+// If the path ends with ".aseprite" or ".ase", the sheet is loaded directly
+// from this Aseprite file, and frame must be zero: each frame of the file
+// (with all visible layers flattened) becomes a frame of the sheet, with its
+// duration, and the tags define the animations. The slices of the file become
+// named pictures (see Named and NineSlice).
+//
+// Sheets declared while the framework is running are loaded immediately.
+func Sheet(path string, frame XY) SheetID {
+	return newSheet(sheet{size: frame}, path)
+}
+
+// SheetFS declares a new sprite sheet loaded from a file system (for example
+// an embed.FS). It is otherwise identical to Sheet.
+func SheetFS(fsys fs.FS, path string, frame XY) SheetID {
+	if fsys == nil {
+		setErr(errors.New("pixel sheet declaration: nil file system"))
+		return noSheet
+	}
+	return newSheet(sheet{fsys: fsys, size: frame}, path)
+}
+
+func newSheet(s sheet, path string) SheetID {
+	if len(sheets) >= maxSheetID {
+		setErr(errors.New("pixel sheet declaration: too many sheets"))
+		return noSheet
+	}
+	if s.size.X < 0 || s.size.Y < 0 || (s.size.X == 0) != (s.size.Y == 0) {
+		setErr(errors.New("pixel sheet declaration: invalid frame size"))
+		return noSheet
+	}
+
+	s.colors = colorMapping
+	sheets = append(sheets, s)
+	sheetPaths = append(sheetPaths, path)
+	a := SheetID(len(sheets) - 1)
+
+	if internal.Running {
+		err := a.load()
+		if err != nil {
+			setErr(internal.Wrap("pixel sheet declaration", err))
+		}
+	}
+	return a
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FrameCount returns the number of frames in the sheet. It is only known once
+// the sheet is loaded.
+func (a SheetID) FrameCount() int {
+	if int(a) >= len(sheets) {
+		return 0
+	}
+	return int(sheets[a].count)
+}
+
+// Frame returns the picture of a frame of the sheet.
+func (a SheetID) Frame(i int) PictureID {
+	if int(a) >= len(sheets) || i < 0 || i >= int(sheets[a].count) {
+		return noPicture
+	}
+	return PictureID(int(sheets[a].first) + i)
+}
+
+// Named returns the picture of a frame of the sheet, given its name in the
+// JSON description (or the picture of a slice, for Aseprite sheets).
+func (a SheetID) Named(name string) PictureID {
+	if int(a) >= len(sheets) {
+		return noPicture
+	}
+	f, ok := sheets[a].names[name]
+	if !ok {
+		return noPicture
+	}
+	return PictureID(sheets[a].first + f)
+}
+
+// NineSlice returns a nine-slice for a slice of an Aseprite sheet, using its
+// nine-patch center. Slices without a center are entirely center (i.e. they
+// are tiled). The sheet must be loaded.
+func (a SheetID) NineSlice(name string) NineSlice {
+	if int(a) >= len(sheets) {
+		return NineSlice{Picture: noPicture}
+	}
+	c, ok := sheets[a].centers[name]
+	if !ok {
+		return NineSlice{Picture: noPicture}
+	}
+	return NineSlice{Picture: a.Named(name), Min: c[0], Max: c[1]}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a SheetID) load() error {
+	s := &sheets[a]
+	if s.loaded {
+		return nil
+	}
+
+	if isAseprite(sheetPaths[a]) {
+		return a.loadAseprite()
+	}
+
+	path := sheetPaths[a] + ".png"
+	f, err := open(s.fsys, path)
+	if err != nil {
+		return internal.Wrap(`while opening sheet "`+path+`"`, err)
+	}
+	defer f.Close() //TODO: error handling
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return internal.Wrap(`decoding sheet "`+path+`"`, err)
+	}
+	m := indexed(img, s.colors)
+
+	// Find the frames
+
+	var rr []image.Rectangle
+	if s.size.X > 0 {
+		b := m.Bounds()
+		for y := b.Min.Y; y+int(s.size.Y) <= b.Max.Y; y += int(s.size.Y) {
+			for x := b.Min.X; x+int(s.size.X) <= b.Max.X; x += int(s.size.X) {
+				rr = append(rr, image.Rect(x, y, x+int(s.size.X), y+int(s.size.Y)))
+			}
+		}
+	} else {
+		rr, err = a.describe()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create a picture for each frame
+
+	s.first = uint16(len(pictures.mapping))
+	for _, r := range rr {
+		if !r.In(m.Bounds()) {
+			return errors.New("impossible to load sheet " + path + " (frame outside of image)")
+		}
+		picture(m.SubImage(r).(*image.Paletted))
+	}
+	s.count = uint16(len(rr))
+	s.loaded = true
+
+	internal.Debug.Printf("Loaded sheet %s (%d frames)", sheetPaths[a], s.count)
+
+	return nil
+}
+
+// describe loads the JSON description of the sheet, and returns the rectangle
+// of each frame.
+func (a SheetID) describe() ([]image.Rectangle, error) {
+	s := &sheets[a]
+	path := sheetPaths[a] + ".json"
+	f, err := open(s.fsys, path)
+	if err != nil {
+		return nil, internal.Wrap(`while opening sheet description "`+path+`"`, err)
+	}
+	defer f.Close()
+
+	var desc sheetDescription
+	d := json.NewDecoder(f)
+	if err := d.Decode(&desc); err != nil {
+		return nil, internal.Wrap(`while parsing sheet description "`+path+`"`, err)
+	}
+
+	rr := make([]image.Rectangle, len(desc.Frames))
+	s.durations = make([]float64, len(desc.Frames))
+	s.names = make(map[string]uint16, len(desc.Frames))
+	for i, fr := range desc.Frames {
+		rr[i] = image.Rect(fr.Frame.X, fr.Frame.Y, fr.Frame.X+fr.Frame.W, fr.Frame.Y+fr.Frame.H)
+		s.durations[i] = float64(fr.Duration) / 1000
+		if fr.Filename != "" {
+			s.names[fr.Filename] = uint16(i)
+		}
+	}
+
+	s.tags = make(map[string]sheetTag, len(desc.Meta.FrameTags))
+	for _, t := range desc.Meta.FrameTags {
+		if t.From < 0 || t.To < t.From || t.To >= len(rr) {
+			return nil, errors.New(`invalid frame range for tag "` + t.Name + `" in ` + path)
+		}
+		st := sheetTag{from: int16(t.From), to: int16(t.To)}
+		switch t.Direction {
+		case "reverse":
+			st.reverse = true
+		case "pingpong":
+			st.mode = PingPong
+		}
+		s.tags[t.Name] = st
+	}
+
+	return rr, nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSheet(t *testing.T) {
+	internal.Config.Headless = true
+	defer func() { internal.Config.Headless = false }()
+
+	// A 4x2 image with 4 frames of 2x1 pixels
+	f := pngFile(t, paletted(4, 2, 1, 1, 2, 2, 3, 3, 4, 4))
+	desc := `{
+		"frames": [
+			{"filename": "idle", "frame": {"x": 2, "y": 1, "w": 2, "h": 1}, "duration": 100},
+			{"filename": "run 1", "frame": {"x": 0, "y": 0, "w": 2, "h": 1}, "duration": 100},
+			{"filename": "run 2", "frame": {"x": 2, "y": 0, "w": 2, "h": 1}, "duration": 200},
+			{"filename": "run 3", "frame": {"x": 0, "y": 1, "w": 2, "h": 1}, "duration": 300}
+		],
+		"meta": {"frameTags": [
+			{"name": "run", "from": 1, "to": 3, "direction": "forward"},
+			{"name": "back", "from": 1, "to": 3, "direction": "reverse"},
+			{"name": "bounce", "from": 1, "to": 3, "direction": "pingpong"}
+		]}
+	}`
+	fsys := fstest.MapFS{
+		"grid.png":      f,
+		"aseprite.png":  f,
+		"aseprite.json": {Data: []byte(desc)},
+	}
+
+	grid := SheetFS(fsys, "grid", XY{2, 1})
+	ase := SheetFS(fsys, "aseprite", XY{})
+
+	if err := setup(); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// Frames
+
+	color := func(p PictureID) uint8 {
+		if p == noPicture {
+			return 0
+		}
+		return pictures.image[p].Pix[0]
+	}
+
+	if grid.FrameCount() != 4 || ase.FrameCount() != 4 {
+		t.Fatalf("frame counts: want 4 and 4, got %d and %d", grid.FrameCount(), ase.FrameCount())
+	}
+	for i, c := range []uint8{1, 2, 3, 4} {
+		if color(grid.Frame(i)) != c {
+			t.Errorf("grid frame %d: want color %d, got %d", i, c, color(grid.Frame(i)))
+		}
+	}
+	if color(ase.Named("idle")) != 4 || color(ase.Named("run 2")) != 2 {
+		t.Errorf("named frames: got colors %d and %d", color(ase.Named("idle")), color(ase.Named("run 2")))
+	}
+	if ase.Named("nope") != noPicture || grid.Frame(4) != noPicture {
+		t.Errorf("missing frames should be noPicture")
+	}
+
+	// Animations
+
+	for _, a := range []struct {
+		name   string
+		anim   Animation
+		times  []float64
+		colors []uint8
+	}{
+		{"grid loop", grid.Animation(1, 3, 0.5, Loop),
+			[]float64{-1, 0, 0.49, 0.5, 1.2, 1.5, 1.6}, []uint8{2, 2, 2, 3, 4, 2, 2}},
+		{"grid once", grid.Animation(1, 3, 0.5, Once),
+			[]float64{0, 1.2, 1.5, 100}, []uint8{2, 4, 4, 4}},
+		{"grid pingpong", grid.Animation(0, 3, 1, PingPong),
+			[]float64{0, 3, 4, 5, 6}, []uint8{1, 4, 3, 2, 1}},
+		{"tag", ase.Tag("run"),
+			[]float64{0, 0.15, 0.35, 0.55, 0.65}, []uint8{1, 2, 3, 3, 1}},
+		{"reverse tag", ase.Tag("back"),
+			[]float64{0, 0.35, 0.55}, []uint8{3, 2, 1}},
+		{"pingpong tag", ase.Tag("bounce"),
+			[]float64{0, 0.35, 0.65, 0.85}, []uint8{1, 3, 2, 1}},
+		{"missing tag", ase.Tag("nope"),
+			[]float64{0}, []uint8{0}},
+	} {
+		for i, tm := range a.times {
+			if c := color(a.anim.Frame(tm)); c != a.colors[i] {
+				t.Errorf("%s at %v: want color %d, got %d", a.name, tm, a.colors[i], c)
+			}
+		}
+	}
+
+	if d := ase.Tag("bounce").Duration(); d < 0.799 || d > 0.801 {
+		t.Errorf("pingpong duration: want 0.8, got %v", d)
+	}
+
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSheetInvalid(t *testing.T) {
+	defer headless(t, XY{2, 2})()
+
+	// A failed declaration returns an ID outside of the registry
+	s := SheetFS(fstest.MapFS{}, "invalid", XY{-1, 2})
+	if Err() == nil {
+		t.Errorf("invalid declaration: no error")
+	}
+	if s.FrameCount() != 0 || s.Frame(0) != noPicture || s.Named("idle") != noPicture ||
+		s.NineSlice("panel").Picture != noPicture {
+		t.Errorf("invalid sheet: unexpected frames")
+	}
+	for _, an := range []Animation{{}, s.Animation(0, 1, 0.1, Loop), s.Tag("run")} {
+		if an.Frame(1) != noPicture || an.Duration() != 0 {
+			t.Errorf("animation of invalid sheet: unexpected frame or duration")
+		}
+		an.Paint(0, XY{}, 1)
+	}
+	if m := NewTilemap(s, XY{2, 2}); m.TileSize() != (XY{}) {
+		t.Errorf("tilemap of invalid sheet: tile size %v", m.TileSize())
+	}
+	render()
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/cozely/cozely/internal"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Snapshot returns a copy of the canvas, as displayed by the last rendered
+// frame, associated with the current palette. The image is the size of the
+// resolution, and its top-left pixel corresponds to canvas coordinates (0, 0).
+//
+// Note that the commands queued during the current frame are not included: to
+// capture a complete frame, call Snapshot from React or Update.
+func Snapshot() *image.Paletted {
+	var c *image.Paletted
+	if internal.Config.Headless {
+		c = software.snapshot()
+	} else {
+		c = renderer.snapshot()
+	}
+	if c == nil {
+		setErr(errors.New("pixel snapshot: no canvas available"))
+		return nil
+	}
+
+	r := Resolution()
+	m := image.NewPaletted(image.Rect(0, 0, int(r.X), int(r.Y)), stdPalette())
+	ox, oy := int(screen.margin.X), int(screen.margin.Y)
+	for y := 0; y < int(r.Y); y++ {
+		for x := 0; x < int(r.X); x++ {
+			if (image.Point{ox + x, oy + y}).In(c.Rect) {
+				m.Pix[x+y*m.Stride] = c.Pix[ox+x+(oy+y)*c.Stride]
+			}
+		}
+	}
+
+	return m
+}
+
+// SaveSnapshot writes a snapshot of the canvas to a PNG file. The path is
+// slash-separated, and relative to the current directory.
+//
+// See Snapshot for details.
+func SaveSnapshot(path string) error {
+	m := Snapshot()
+	if m == nil {
+		return errors.New("pixel snapshot: no canvas available")
+	}
+
+	f, err := os.Create(filepath.FromSlash(path))
+	if err != nil {
+		return internal.Wrap(`while creating snapshot file "`+path+`"`, err)
+	}
+
+	err = png.Encode(f, m)
+	if err != nil {
+		f.Close()
+		return internal.Wrap("encoding snapshot", err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,543 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"image"
+
+	"github.com/cozely/cozely/x/gl"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// minDepth is the value of the depth buffer after a clear. Note that layers
+// below it are never drawn (they fall outside of the GPU clip space).
+const minDepth = -0x7FFF
+
+var software = swRenderer{}
+
+// swRenderer is a pure-Go implementation of the drawing pipeline, used when the
+// framework is headless. It executes the command queue of the GPU renderer, and
+// reproduces the semantics of the GLSL shaders: color index 0 is discarded, and
+// layers are depth-tested with the "greater or equal" comparison.
+type swRenderer struct {
+	canvas    *image.Paletted
+	filter    []uint8 // one remapping table per pixel of the canvas
+	depth     []int16
+	offscreen []swCanvas // indexed by CanvasID (the first one is unused)
+	bins      []*image.Paletted
+	dest      swCanvas        // target of the current view
+	clip      image.Rectangle // of the current view
+	toFilter  bool            // draw in the filter instead of the canvas
+}
+
+type swCanvas struct {
+	image *image.Paletted
+	depth []int16
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a *swRenderer) setup() error {
+	a.bins = a.bins[:0]
+	for i := int16(0); i < pictures.atlas.BinCount(); i++ {
+		m, err := paintBin(i)
+		if err != nil {
+			return err
+		}
+		a.bins = append(a.bins, m)
+	}
+
+	a.offscreen = a.offscreen[:0]
+	for i := range canvases.size {
+		a.newCanvas(CanvasID(i))
+	}
+
+	renderer.clearQueued = true
+
+	return nil
+}
+
+func (a *swRenderer) cleanup() error {
+	a.canvas = nil
+	a.filter = nil
+	a.depth = nil
+	a.offscreen = nil
+	a.dest = swCanvas{}
+	a.bins = nil
+	return nil
+}
+
+// newCanvas allocates an off-screen canvas.
+func (a *swRenderer) newCanvas(c CanvasID) {
+	s := canvases.size[c]
+	m := image.NewPaletted(image.Rect(0, 0, int(s.X), int(s.Y)), nil)
+	a.offscreen = append(a.offscreen, swCanvas{
+		image: m,
+		depth: make([]int16, len(m.Pix)),
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// update copies the pictures loaded while running into the bins.
+func (a *swRenderer) update() {
+	w, h := pictures.atlas.BinSize()
+	for i := int16(len(a.bins)); i < pictures.atlas.BinCount(); i++ {
+		a.bins = append(a.bins, image.NewPaletted(image.Rect(0, 0, int(w), int(h)), nil))
+	}
+
+	for _, r := range pictures.pending {
+		m := pictures.mapping[r]
+		if m.w == 0 || m.h == 0 || pictures.image[r] == nil {
+			// Unloaded since
+			continue
+		}
+		b, src := a.bins[m.bin], pictures.image[r]
+		for y := 0; y < int(m.h); y++ {
+			copy(
+				b.Pix[int(m.x)+(int(m.y)+y)*b.Stride:int(m.x)+int(m.w)+(int(m.y)+y)*b.Stride],
+				src.Pix[y*src.Stride:],
+			)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a *swRenderer) adjust() {
+	a.canvas = image.NewPaletted(
+		image.Rect(0, 0, int(screen.size.X), int(screen.size.Y)),
+		stdPalette(),
+	)
+	a.clip = a.canvas.Rect
+	a.filter = make([]uint8, len(a.canvas.Pix))
+	a.depth = make([]int16, len(a.canvas.Pix))
+	for i := range a.depth {
+		a.depth[i] = minDepth
+	}
+	palette.dirty = true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a *swRenderer) render() error {
+	if a.canvas == nil {
+		return nil
+	}
+
+	// Convert the current palette
+
+	if palette.dirty {
+		a.canvas.Palette = stdPalette()
+		palette.dirty = false
+	}
+
+	if renderer.clearQueued {
+		renderer.clearQueued = false
+		for i := range a.canvas.Pix {
+			a.canvas.Pix[i] = uint8(renderer.clearColor)
+			a.filter[i] = uint8(NoRemap)
+			a.depth[i] = minDepth
+		}
+	}
+
+	for i, c := range canvases.clear {
+		if c < 0 {
+			continue
+		}
+		canvases.clear[i] = -1
+		o := a.offscreen[i]
+		for j := range o.image.Pix {
+			o.image.Pix[j] = uint8(c)
+			o.depth[j] = minDepth
+		}
+		a.publish(CanvasID(i))
+	}
+
+	// Execute all pending commands
+
+	eachView(func(v view, end uint32) {
+		_, m := v.target.frame()
+		a.dest = swCanvas{a.canvas, a.depth}
+		if v.target != Screen {
+			a.dest = a.offscreen[v.target]
+		}
+		a.toFilter = v.filter
+		a.execute(renderer.commands[v.first:end], m.Plus(v.offset), v.clip)
+		if v.target != Screen {
+			a.publish(v.target)
+		}
+	})
+	a.clip = a.canvas.Rect
+
+	renderer.commands = renderer.commands[:0]
+	renderer.parameters = renderer.parameters[:0]
+	renderer.views = renderer.views[:0]
+
+	return nil
+}
+
+// execute draws the commands queued with the same view. The origin is the
+// position of canvas coordinates (0, 0) in the target.
+func (a *swRenderer) execute(commands []gl.DrawIndirectCommand, origin XY, clip image.Rectangle) {
+	a.clip = clip
+	mx, my := int(origin.X), int(origin.Y)
+
+	for _, c := range commands {
+		prm := renderer.parameters[c.BaseInstance&0xFFFFFF:]
+		n := int(c.InstanceCount)
+
+		switch c.BaseInstance >> 24 {
+
+		case cmdPicture:
+			for i := 0; i < n; i++ {
+				p := prm[4*i : 4*i+4]
+				a.picture(PictureID(p[0]), p[1], mx+int(p[2]), my+int(p[3]), 0)
+			}
+
+		case cmdPictureExt:
+			for i := 0; i < n; i++ {
+				p := prm[6*i : 6*i+6]
+				a.pictureExt(PictureID(p[0]), p[1], mx+int(p[2]), my+int(p[3]), uint16(p[4]), RemapID(p[5]))
+			}
+
+		case cmdTilemap:
+			z, x, y, w, h, cols := prm[0], mx+int(prm[1]), my+int(prm[2]), int(prm[3]), int(prm[4]), int(prm[5])
+			for i := 0; i < n; i++ {
+				p := prm[6+2*i : 6+2*i+2]
+				a.pictureExt(PictureID(p[0]), z, x+(i%cols)*w, y+(i/cols)*h, 1<<8|uint16(p[1]), NoRemap)
+			}
+
+		case cmdSlice:
+			for i := 0; i < n; i++ {
+				p := prm[11*i : 11*i+11]
+				a.slice(PictureID(p[0]), p[1],
+					mx+int(p[2]), my+int(p[3]), int(p[4]), int(p[5]),
+					int(p[6]), int(p[7]), int(p[8]), int(p[9]),
+					p[10] != 0)
+			}
+
+		case cmdText:
+			ci, z, y := uint8(prm[0]), prm[1], my+int(prm[2])
+			for i := 0; i < n; i++ {
+				p := prm[3+2*i : 3+2*i+2]
+				a.picture(PictureID(p[0]), z, mx+int(p[1]), y, ci)
+			}
+
+		case cmdPoint:
+			for i := 0; i < n; i++ {
+				p := prm[4*i : 4*i+4]
+				a.plot(mx+int(p[2]), my+int(p[3]), p[1], uint8(p[0]))
+			}
+
+		case cmdLines:
+			ci, z := uint8(prm[0]), prm[1]
+			for i := 0; i < n; i++ {
+				p := prm[2+2*i : 2+2*i+4]
+				a.line(mx+int(p[0]), my+int(p[1]), mx+int(p[2]), my+int(p[3]), z, ci)
+			}
+
+		case cmdTriangles:
+			ci, z := uint8(prm[0]), prm[1]
+			v := prm[2 : 2+2*c.VertexCount]
+			for i := 0; i+5 < len(v); i += 2 {
+				a.triangle(
+					mx+int(v[i]), my+int(v[i+1]),
+					mx+int(v[i+2]), my+int(v[i+3]),
+					mx+int(v[i+4]), my+int(v[i+5]),
+					z, ci,
+				)
+			}
+
+		case cmdBox:
+			for i := 0; i < n; i++ {
+				p := prm[7*i : 7*i+7]
+				a.box(
+					uint8(uint16(p[0])>>8), uint8(p[0]), p[1], int(p[2]),
+					mx+int(p[3]), my+int(p[4]), mx+int(p[5]), my+int(p[6]),
+				)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (a *swRenderer) snapshot() *image.Paletted {
+	if a.canvas == nil {
+		return nil
+	}
+	m := *a.canvas
+	m.Pix = append([]uint8(nil), a.canvas.Pix...)
+	applyFilter(m.Pix, a.filter)
+	return &m
+}
+
+func (a *swRenderer) snapshotCanvas(c CanvasID) *image.Paletted {
+	if int(c) >= len(a.offscreen) {
+		return nil
+	}
+	m := *a.offscreen[c].image
+	m.Pix = append([]uint8(nil), m.Pix...)
+	return &m
+}
+
+// publish copies an off-screen canvas to its picture in the atlas.
+func (a *swRenderer) publish(c CanvasID) {
+	m := pictures.mapping[canvases.picture[c]]
+	if m.w == 0 || m.h == 0 || int(m.bin) >= len(a.bins) {
+		// Unloaded
+		return
+	}
+	b, src := a.bins[m.bin], a.offscreen[c].image
+	for y := 0; y < int(m.h); y++ {
+		copy(
+			b.Pix[int(m.x)+(int(m.y)+y)*b.Stride:int(m.x)+int(m.w)+(int(m.y)+y)*b.Stride],
+			src.Pix[y*src.Stride:],
+		)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// plot writes a single pixel, if it passes the depth test. Only the canvas
+// updates the depth.
+func (a *swRenderer) plot(x, y int, z int16, c uint8) {
+	if c == 0 || z < minDepth {
+		return
+	}
+	r := a.clip
+	if x < r.Min.X || x >= r.Max.X || y < r.Min.Y || y >= r.Max.Y {
+		return
+	}
+	i := x + y*a.dest.image.Stride
+	if z < a.dest.depth[i] {
+		return
+	}
+	if a.toFilter {
+		a.filter[i] = c
+		return
+	}
+	a.dest.image.Pix[i] = c
+	a.dest.depth[i] = z
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// picture copies a picture from the atlas. If the tint is non-zero, the color
+// indices are shifted as for text.
+func (a *swRenderer) picture(p PictureID, z int16, x, y int, tint uint8) {
+	m := pictures.mapping[p]
+	if int(m.bin) >= len(a.bins) {
+		return
+	}
+	b := a.bins[m.bin]
+	for j := 0; j < int(m.h); j++ {
+		for i := 0; i < int(m.w); i++ {
+			c := uint(b.Pix[int(m.x)+i+(int(m.y)+j)*b.Stride])
+			if c == 0 {
+				continue
+			}
+			c += uint(tint)
+			if c > 255 {
+				c -= 255
+			}
+			a.plot(x+i, y+j, z, uint8(c))
+		}
+	}
+}
+
+// pictureExt copies a transformed picture from the atlas.
+func (a *swRenderer) pictureExt(p PictureID, z int16, x, y int, flags uint16, r RemapID) {
+	m := pictures.mapping[p]
+	if int(m.bin) >= len(a.bins) || int(r) >= len(remaps.tables) {
+		return
+	}
+	b := a.bins[m.bin]
+	s := int(flags >> 8)
+	w, h := int(m.w), int(m.h)
+	if flags&transpose != 0 {
+		w, h = h, w
+	}
+	for j := 0; j < h*s; j++ {
+		for i := 0; i < w*s; i++ {
+			u, v := i/s, j/s
+			if flags&flipX != 0 {
+				u = w - 1 - u
+			}
+			if flags&flipY != 0 {
+				v = h - 1 - v
+			}
+			if flags&transpose != 0 {
+				u, v = v, u
+			}
+			c := b.Pix[int(m.x)+u+(int(m.y)+v)*b.Stride]
+			a.plot(x+i, y+j, z, uint8(remaps.tables[r][c]))
+		}
+	}
+}
+
+// slice fills a rectangle with a part of a picture, either tiled or stretched
+// (with the same rounding as the interpolation of the GPU).
+func (a *swRenderer) slice(p PictureID, z int16, x, y, w, h int, sx, sy, sw, sh int, stretch bool) {
+	m := pictures.mapping[p]
+	if int(m.bin) >= len(a.bins) || sw <= 0 || sh <= 0 {
+		return
+	}
+	b := a.bins[m.bin]
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			u, v := i%sw, j%sh
+			if stretch {
+				u = int((float32(i) + 0.5) * float32(sw) / float32(w))
+				v = int((float32(j) + 0.5) * float32(sh) / float32(h))
+			}
+			c := b.Pix[int(m.x)+sx+u+(int(m.y)+sy+v)*b.Stride]
+			a.plot(x+i, y+j, z, c)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// line draws a segment, with both ends included. It reproduces the test made
+// by the fragment shader, including the single precision rounding.
+func (a *swRenderer) line(x1, y1, x2, y2 int, z int16, c uint8) {
+	dx, dy := x2-x1, y2-y1
+	switch {
+	case dx == 0 && dy == 0:
+		return
+
+	case abs(dx) < abs(dy):
+		s := float32(dx) / float32(dy)
+		for j := 0; j <= abs(dy); j++ {
+			y := j * sign(dy)
+			a.plot(x1+round32(s*float32(y)), y1+y, z, c)
+		}
+
+	default:
+		s := float32(dy) / float32(dx)
+		for i := 0; i <= abs(dx); i++ {
+			x := i * sign(dx)
+			a.plot(x1+x, y1+round32(s*float32(x)), z, c)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// triangle fills a triangle, using a top-left rule for the pixels that fall
+// exactly on an edge.
+func (a *swRenderer) triangle(x1, y1, x2, y2, x3, y3 int, z int16, c uint8) {
+	area := edge(x1, y1, x2, y2, x3, y3)
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		x2, y2, x3, y3 = x3, y3, x2, y2
+	}
+
+	b1 := topLeft(x2, y2, x3, y3)
+	b2 := topLeft(x3, y3, x1, y1)
+	b3 := topLeft(x1, y1, x2, y2)
+
+	r := image.Rect(min3(x1, x2, x3), min3(y1, y2, y3), max3(x1, x2, x3)+1, max3(y1, y2, y3)+1)
+	r = r.Intersect(a.clip)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			w1 := edge(x2, y2, x3, y3, x, y)
+			w2 := edge(x3, y3, x1, y1, x, y)
+			w3 := edge(x1, y1, x2, y2, x, y)
+			if inside(w1, b1) && inside(w2, b2) && inside(w3, b3) {
+				a.plot(x, y, z, c)
+			}
+		}
+	}
+}
+
+// edge returns a positive value if (x, y) is on the inner side of the edge
+// (x1, y1)-(x2, y2) of a clockwise triangle (on the canvas, where Y is
+// pointing down).
+func edge(x1, y1, x2, y2, x, y int) int {
+	return (x2-x1)*(y-y1) - (y2-y1)*(x-x1)
+}
+
+// topLeft returns true if the edge of a clockwise triangle is either a top
+// edge or a left edge.
+func topLeft(x1, y1, x2, y2 int) bool {
+	return (y1 == y2 && x2 > x1) || y2 < y1
+}
+
+func inside(w int, topleft bool) bool {
+	return w > 0 || (w == 0 && topleft)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// box draws a box with (optional) cut corners, like the fragment shader.
+func (a *swRenderer) box(fg, bg uint8, z int16, corner int, x1, y1, x2, y2 int) {
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			dx, dy := x-x1, y-y1
+			if x2-x < dx {
+				dx = x2 - x
+			}
+			if y2-y < dy {
+				dy = y2 - y
+			}
+			switch {
+			case dx+dy < corner:
+				continue
+			case dx+dy == corner || dx < 1 || dy < 1:
+				a.plot(x, y, z, fg)
+			default:
+				a.plot(x, y, z, bg)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func sign(a int) int {
+	if a < 0 {
+		return -1
+	}
+	return 1
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func max3(a, b, c int) int {
+	if b > a {
+		a = b
+	}
+	if c > a {
+		a = c
+	}
+	return a
+}
+
+func round32(a float32) int {
+	if a < 0 {
+		return -int(-a + 0.5)
+	}
+	return int(a + 0.5)
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"bytes"
+	"image"
+	stdcolor "image/color"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cozely/cozely/color"
+	"github.com/cozely/cozely/internal"
+	"github.com/cozely/cozely/x/atlas"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// headless prepares the package for a test with the software renderer, on a
+// canvas of the specified size. The returned function cleans up the registries
+// and restores the previous state of the screen; it should be deferred.
+func headless(t *testing.T, size XY) func() {
+	saved, h := screen, internal.Config.Headless
+	internal.Config.Headless = true
+	screen.size = size
+	screen.margin = XY{}
+	if err := setup(); err != nil {
+		t.Fatal(err)
+	}
+	adjustScreenTextures()
+	return func() {
+		cleanup()
+		screen = saved
+		internal.Config.Headless = h
+	}
+}
+
+// paletted returns an image of the specified size and pixels, with a gray
+// palette so that indices are kept when the image is encoded and decoded.
+func paletted(w, h int, pix ...uint8) *image.Paletted {
+	pal := make(stdcolor.Palette, 256)
+	for i := range pal {
+		pal[i] = stdcolor.Gray{uint8(i)}
+	}
+	m := image.NewPaletted(image.Rect(0, 0, w, h), pal)
+	copy(m.Pix, pix)
+	return m
+}
+
+// pngFile returns an in-memory file containing the PNG encoding of an image.
+func pngFile(t *testing.T, m image.Image) *fstest.MapFile {
+	var b bytes.Buffer
+	if err := png.Encode(&b, m); err != nil {
+		t.Fatal(err)
+	}
+	return &fstest.MapFile{Data: b.Bytes()}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSoftwareRenderer(t *testing.T) {
+	defer headless(t, XY{12, 8})()
+
+	Clear(1)
+	Box(2, 3, 0, 1, XY{0, 0}, XY{4, 3})
+	Point(4, 1, XY{2, 1})
+	Point(5, -1, XY{1, 1})
+	Lines(6, 0, XY{6, 0}, XY{11, 2})
+	Triangles(7, 0, XY{6, 4}, XY{11, 4}, XY{6, 7})
+	render()
+
+	want := `
+		122211661111
+		234321116611
+		233321111166
+		122211111111
+		111111777771
+		111111777711
+		111111771111
+		111111111111`
+
+	got := ""
+	for y := 0; y < software.canvas.Rect.Dy(); y++ {
+		got += "\n"
+		for x := 0; x < software.canvas.Rect.Dx(); x++ {
+			got += string("0123456789ABCDEF"[software.canvas.Pix[x+y*software.canvas.Stride]])
+		}
+	}
+	want = strings.Replace(want, "\t", "", -1)
+	if got != want {
+		t.Errorf("software canvas:\nwant:%s\ngot:%s", want, got)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSoftwareSnapshot(t *testing.T) {
+	defer headless(t, XY{6, 4})()
+	screen.margin = XY{1, 1}
+	screen.resolution = XY{4, 2}
+	adjustScreenTextures()
+
+	Clear(1)
+	Point(7, 0, XY{0, 0})
+	Point(8, 0, XY{3, 1})
+	render()
+
+	m := Snapshot()
+	if m == nil {
+		t.Fatal("no snapshot")
+	}
+	if m.Rect != image.Rect(0, 0, 4, 2) {
+		t.Errorf("snapshot size: want %v, got %v", image.Rect(0, 0, 4, 2), m.Rect)
+	}
+	want := []uint8{7, 1, 1, 1, 1, 1, 1, 8}
+	for i := range want {
+		if m.Pix[i] != want[i] {
+			t.Errorf("snapshot pixels: want %v, got %v", want, m.Pix)
+			break
+		}
+	}
+	if m.Palette[7] != color.SRGBA8of(DefaultPalette.Colors[6]) {
+		t.Errorf("snapshot palette: want %v, got %v", DefaultPalette.Colors[6], m.Palette[7])
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSoftwareRuntimePictures(t *testing.T) {
+	defer headless(t, XY{4, 2})()
+	pictures.atlas = atlas.New(4, 4)
+
+	internal.Running = true
+	defer func() { internal.Running = false }()
+
+	m := image.NewPaletted(image.Rect(0, 0, 2, 1), nil)
+	m.Pix = []uint8{3, 4}
+	p := picture(m)
+	q := picture(image.NewPaletted(image.Rect(0, 0, 4, 4), nil)) // new bin
+	if !p.IsLoaded() || !q.IsLoaded() || pictures.atlas.BinCount() != 2 {
+		t.Fatalf("runtime pictures not packed (%d bins)", pictures.atlas.BinCount())
+	}
+
+	pixels := func() []uint8 {
+		Clear(1)
+		p.Paint(0, XY{1, 1})
+		render()
+		return append([]uint8(nil), software.canvas.Pix...)
+	}
+
+	if got, want := pixels(), []uint8{1, 1, 1, 1, 1, 3, 4, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded picture: want %v, got %v", want, got)
+	}
+
+	p.Unload()
+	if p.IsLoaded() {
+		t.Errorf("picture still loaded")
+	}
+	if got, want := pixels(), []uint8{1, 1, 1, 1, 1, 1, 1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unloaded picture: want %v, got %v", want, got)
+	}
+
+	m.Pix = []uint8{5, 6}
+	p.Reload()
+	if got, want := pixels(), []uint8{1, 1, 1, 1, 1, 5, 6, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded picture: want %v, got %v", want, got)
+	}
+	if err := Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSoftwarePictureExt(t *testing.T) {
+	m := image.NewPaletted(image.Rect(0, 0, 3, 2), nil)
+	m.Pix = []uint8{
+		2, 3, 4,
+		5, 6, 0,
+	}
+	p := PictureImage(m)
+	r := RemapRange(2, 3, 8)
+
+	defer headless(t, XY{6, 6})()
+
+	for _, c := range []struct {
+		o     Orientation
+		scale int16
+		r     RemapID
+		want  string
+	}{
+		{Upright, 1, NoRemap, "234\n560"},
+		{FlipX, 1, NoRemap, "432\n065"},
+		{FlipY, 1, NoRemap, "560\n234"},
+		{Rotate180, 1, NoRemap, "065\n432"},
+		{FlipDiagonal, 1, NoRemap, "25\n36\n40"},
+		{Rotate90, 1, NoRemap, "52\n63\n04"},
+		{Rotate270, 1, NoRemap, "40\n36\n25"},
+		{FlipAntiDiagonal, 1, NoRemap, "04\n63\n52"},
+		{Upright, 2, NoRemap, "223344\n223344\n556600\n556600"},
+		{Upright, 1, r, "894\n560"},
+	} {
+		Clear(0)
+		p.PaintExt(0, XY{0, 0}, c.o, c.scale, c.r)
+		render()
+
+		s := p.SizeExt(c.o, c.scale)
+		got := ""
+		for y := 0; y < int(s.Y); y++ {
+			if y > 0 {
+				got += "\n"
+			}
+			for x := 0; x < int(s.X); x++ {
+				got += string("0123456789ABCDEF"[software.canvas.Pix[x+y*software.canvas.Stride]])
+			}
+		}
+		if got != c.want {
+			t.Errorf("PaintExt(%d, %d, %d):\nwant:\n%s\ngot:\n%s", c.o, c.scale, c.r, c.want, got)
+		}
+	}
+}
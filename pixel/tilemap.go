@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A Tilemap is a grid of tiles, each of them a frame of a sprite sheet (the
+// tileset). It is painted with a single GPU command, and only the tiles
+// visible on the canvas are sent.
+type Tilemap struct {
+	tileset SheetID
+	size    XY
+	tiles   []Tile
+	anims   map[int16]Animation
+}
+
+// A Tile is a cell of a tilemap.
+type Tile struct {
+	Frame       int16 // frame of the tileset, or -1 for an empty cell
+	Orientation Orientation
+}
+
+// NoTile is the value of empty cells.
+var NoTile = Tile{Frame: -1}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewTilemap returns a new tilemap of the given size (in tiles), with all cells
+// empty. All frames of the tileset should have the same size; rotated
+// orientations also require square tiles.
+func NewTilemap(tileset SheetID, size XY) *Tilemap {
+	if size.X < 0 || size.Y < 0 {
+		size = XY{}
+	}
+	m := Tilemap{
+		tileset: tileset,
+		size:    size,
+		tiles:   make([]Tile, int(size.X)*int(size.Y)),
+		anims:   map[int16]Animation{},
+	}
+	for i := range m.tiles {
+		m.tiles[i] = NoTile
+	}
+	return &m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Size returns the size of the tilemap, in tiles.
+func (m *Tilemap) Size() XY {
+	return m.size
+}
+
+// TileSize returns the size of the tiles, in pixels. It is only known once the
+// tileset is loaded.
+func (m *Tilemap) TileSize() XY {
+	if int(m.tileset) >= len(sheets) {
+		return XY{}
+	}
+	if s := sheets[m.tileset].size; !s.Null() {
+		return s
+	}
+	return m.tileset.Frame(0).Size()
+}
+
+// Tile returns the tile in a cell, or NoTile if the cell is outside of the map.
+func (m *Tilemap) Tile(cell XY) Tile {
+	if cell.X < 0 || cell.Y < 0 || cell.X >= m.size.X || cell.Y >= m.size.Y {
+		return NoTile
+	}
+	return m.tiles[int(cell.X)+int(cell.Y)*int(m.size.X)]
+}
+
+// Set changes the tile in a cell. Cells outside of the map are ignored.
+func (m *Tilemap) Set(cell XY, t Tile) {
+	if cell.X < 0 || cell.Y < 0 || cell.X >= m.size.X || cell.Y >= m.size.Y {
+		return
+	}
+	m.tiles[int(cell.X)+int(cell.Y)*int(m.size.X)] = t
+}
+
+// Animate replaces all tiles using a frame of the tileset by an animation. The
+// animation should use frames of the same size. Use a zero Animation to stop.
+func (m *Tilemap) Animate(frame int16, a Animation) {
+	if a == (Animation{}) {
+		delete(m.anims, frame)
+		return
+	}
+	m.anims[frame] = a
+}
+
+// Cell returns the cell containing a point, given in pixels relative to the
+// top-left corner of the map.
+func (m *Tilemap) Cell(p XY) XY {
+	ts := m.TileSize()
+	if ts.X == 0 || ts.Y == 0 {
+		return XY{}
+	}
+	c := XY{p.X / ts.X, p.Y / ts.Y}
+	if p.X < 0 && p.X%ts.X != 0 {
+		c.X--
+	}
+	if p.Y < 0 && p.Y%ts.Y != 0 {
+		c.Y--
+	}
+	return c
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Paint queues a GPU command to draw the visible part of the tilemap on the
+// canvas (or in the viewport of the current camera), with its top-left corner
+// at pos. The animated tiles display their frame at time t, in seconds.
+func (m *Tilemap) Paint(layer int16, pos XY, t float64) {
+	ts := m.TileSize()
+	if ts.X <= 0 || ts.Y <= 0 {
+		return
+	}
+
+	// Find the visible cells
+
+	vmin, vmax := visible()
+	first := m.Cell(vmin.Minus(pos))
+	last := m.Cell(vmax.Minus(pos).MinusS(1))
+	if first.X < 0 {
+		first.X = 0
+	}
+	if first.Y < 0 {
+		first.Y = 0
+	}
+	if last.X >= m.size.X {
+		last.X = m.size.X - 1
+	}
+	if last.Y >= m.size.Y {
+		last.Y = m.size.Y - 1
+	}
+	if last.X < first.X || last.Y < first.Y {
+		return
+	}
+	cols, rows := last.X-first.X+1, last.Y-first.Y+1
+
+	// Resolve the animations
+
+	frames := make(map[int16]PictureID, len(m.anims))
+	for f, a := range m.anims {
+		frames[f] = a.Frame(t)
+	}
+
+	// Queue the command
+
+	o := pos.Plus(first.TimesXY(ts))
+	prm := make([]int16, 0, 6+2*int(cols)*int(rows)) //TODO: remove alloc
+	prm = append(prm, layer, o.X, o.Y, ts.X, ts.Y, cols)
+	for y := first.Y; y <= last.Y; y++ {
+		for x := first.X; x <= last.X; x++ {
+			tl := m.tiles[int(x)+int(y)*int(m.size.X)]
+			p, ok := frames[tl.Frame]
+			if !ok {
+				p = m.tileset.Frame(int(tl.Frame))
+			}
+			prm = append(prm, int16(p), int16(tl.Orientation&0x07))
+		}
+	}
+	renderer.command(cmdTilemap, 4, uint32(cols)*uint32(rows), prm...)
+}
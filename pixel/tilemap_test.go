@@ -0,0 +1,65 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package pixel
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTilemap(t *testing.T) {
+	// Three 2x2 tiles: plain 2, plain 3, and a tile with a 4 in one corner
+	m := paletted(6, 2,
+		2, 2, 3, 3, 4, 5,
+		2, 2, 3, 3, 5, 5)
+	ts := SheetFS(fstest.MapFS{"tiles.png": pngFile(t, m)}, "tiles", XY{2, 2})
+
+	defer headless(t, XY{5, 4})()
+
+	tm := NewTilemap(ts, XY{10, 10})
+	tm.Set(XY{0, 0}, Tile{Frame: 0})
+	tm.Set(XY{1, 0}, Tile{Frame: 2, Orientation: FlipX})
+	tm.Set(XY{2, 0}, Tile{Frame: 2, Orientation: Rotate90})
+	tm.Set(XY{1, 1}, Tile{Frame: 1})
+	tm.Set(XY{9, 9}, Tile{Frame: 1})
+	tm.Animate(1, ts.Animation(0, 1, 1, Loop))
+
+	if tm.Tile(XY{1, 0}) != (Tile{2, FlipX}) || tm.Tile(XY{-1, 0}) != NoTile || tm.Tile(XY{3, 3}) != NoTile {
+		t.Errorf("unexpected tiles")
+	}
+	if c := tm.Cell(XY{-1, 3}); c != (XY{-1, 1}) {
+		t.Errorf("Cell: want %v, got %v", XY{-1, 1}, c)
+	}
+
+	for _, c := range []struct {
+		pos  XY
+		t    float64
+		want string
+	}{
+		{XY{0, 0}, 0, "22545\n22555\n11221\n11221"},
+		{XY{-1, 0}, 1.5, "25454\n25555\n13311\n13311"},
+	} {
+		Clear(1)
+		tm.Paint(0, c.pos, c.t)
+		if len(renderer.commands) != 1 {
+			t.Errorf("want a single command, got %d", len(renderer.commands))
+		}
+		render()
+
+		got := ""
+		for y := 0; y < 4; y++ {
+			if y > 0 {
+				got += "\n"
+			}
+			for x := 0; x < 5; x++ {
+				got += string("0123456789"[software.canvas.Pix[x+y*software.canvas.Stride]])
+			}
+		}
+		if got != c.want {
+			t.Errorf("Paint(%v, %v):\nwant:\n%s\ngot:\n%s", c.pos, c.t, c.want, got)
+		}
+	}
+}
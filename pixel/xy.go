@@ -23,11 +23,11 @@ type XY struct {
 func XYof(v coord.Coordinates) XY {
 	switch v := v.(type) {
 	case window.XY:
-		v = v.Minus(screen.border).Slash(screen.zoom)
-		if !screen.resolution.Null() {
-			return XY(v).Minus(screen.margin)
-		}
-		return XY(v)
+		v = v.Minus(screen.border)
+		return XY{
+			int16(math.Floor(float64(v.X) / screen.scale.X)),
+			int16(math.Floor(float64(v.Y) / screen.scale.Y)),
+		}.Minus(screen.margin)
 	default:
 		x, y, _ := v.Cartesian()
 		return XY{int16(x), int16(y)}
@@ -36,12 +36,11 @@ func XYof(v coord.Coordinates) XY {
 
 // WindowXY takes coordinates in canvas space and returns them in window space.
 func (a XY) WindowXY() window.XY {
-	if !screen.resolution.Null() {
-		a = a.Times(screen.zoom)
-		return window.XY(a).Plus(screen.border)
-	}
-	a = a.Plus(screen.margin).Times(screen.zoom)
-	return window.XY(a).Plus(screen.border)
+	a = a.Plus(screen.margin)
+	return window.XY{
+		int16(float64(a.X) * screen.scale.X),
+		int16(float64(a.Y) * screen.scale.Y),
+	}.Plus(screen.border)
 }
 
 // RoundXYof returns an integer vector corresponding to the first two
@@ -138,13 +138,14 @@ func Run(loop GameLoop) (err error) {
 		// Update and Events
 
 		internal.UpdateLag += internal.RenderDelta
+		internal.ReactTime, internal.UpdateTime = 0, 0
 		//TODO: ProcessEvents should always be called with GameTime = now!
 		if internal.UpdateLag < internal.UpdateStep {
 			// Process events even if there is no Update this frame
 			internal.GameTime = now //TODO: check if correct
 			internal.ProcessEvents(window.Events)
 			internal.InputNewFrame()
-			internal.Loop.React()
+			react()
 		}
 		for internal.UpdateLag >= internal.UpdateStep {
 			// Do the Time Step
@@ -154,14 +155,17 @@ func Run(loop GameLoop) (err error) {
 			// Events
 			internal.ProcessEvents(window.Events)
 			internal.InputNewFrame()
-			internal.Loop.React()
+			react()
 			// Update
+			t := internal.GetSeconds()
 			internal.Loop.Update()
+			internal.UpdateTime += internal.GetSeconds() - t
 		}
 
 		// Render
 
 		//TODO: render before react and update?
+		t := internal.GetSeconds()
 		err = internal.GLPrerender()
 		if err != nil {
 			return err
@@ -172,6 +176,7 @@ func Run(loop GameLoop) (err error) {
 		if err != nil {
 			return err
 		}
+		internal.RenderTime = internal.GetSeconds() - t
 
 		internal.SwapWindow()
 
@@ -190,6 +195,13 @@ func Run(loop GameLoop) (err error) {
 	return stopErr
 }
 
+// react calls the React method of the loop, and measures its duration.
+func react() {
+	t := internal.GetSeconds()
+	internal.Loop.React()
+	internal.ReactTime += internal.GetSeconds() - t
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // Goto replaces the current running loop with l. The change take place at next
@@ -240,6 +252,14 @@ func RenderStats() (t float64, overruns int) {
 	return frAverage, xrunPrevious
 }
 
+// FrameTimes returns the time spent in React and Update during the current
+// frame (both can be called several times per frame), and the time spent in
+// Render during the previous frame (including the execution of the drawing
+// commands).
+func FrameTimes() (react, update, render float64) {
+	return internal.ReactTime, internal.UpdateTime, internal.RenderTime
+}
+
 func countFrames() {
 	frCount++
 	frSum += internal.RenderDelta
@@ -72,6 +72,9 @@ func (a *Atlas) Unused() int {
 // Pack fits all the rectangles in the atlas. New bins are added when needed. It
 // calls the Put method of each image with the corresponding mapping
 // information.
+//
+// Pack can be called several times: the new rectangles are placed in the space
+// left free by the previous calls (including space freed by Remove).
 func (a *Atlas) Pack(rectangles []uint32, size SizeFn, put PutFn) {
 	sort.Slice(rectangles, func(i, j int) bool {
 		wi, hi := size(rectangles[i])
@@ -107,6 +110,23 @@ func (a *Atlas) Pack(rectangles []uint32, size SizeFn, put PutFn) {
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// Remove frees the space used by a rectangle in one of the bins, so that it can
+// be reused by subsequent calls to Pack. It returns false if the rectangle was
+// not found in the bin.
+func (a *Atlas) Remove(bin int16, rect uint32) bool {
+	if bin < 0 || int(bin) >= len(a.bins) {
+		return false
+	}
+	n := a.bins[bin].remove(rect)
+	if n == nil {
+		return false
+	}
+	a.ideal -= int(n.w) * int(n.h)
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 // Paint iterates on all images mapped to the specified bin, and call their own
 // Paint method.
 func (a *Atlas) Paint(bin int16, dest interface{}, paint PaintFn) error {
@@ -0,0 +1,93 @@
+// Copyright (c) 2018-2018 Laurent Moussault. All rights reserved.
+// Licensed under a simplified BSD license (see LICENSE file).
+
+package atlas
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type placement struct {
+	bin  int16
+	x, y int16
+}
+
+// testPack packs rectangles of the given sizes (the rectangle IDs are their
+// indices), and records where they are put.
+func testPack(a *Atlas, sizes [][2]int16, rects []uint32, placed map[uint32]placement) {
+	a.Pack(
+		rects,
+		func(r uint32) (int16, int16) { return sizes[r][0], sizes[r][1] },
+		func(r uint32, bin int16, x, y int16) { placed[r] = placement{bin, x, y} },
+	)
+}
+
+func painted(a *Atlas, bin int16) map[uint32]bool {
+	m := map[uint32]bool{}
+	a.Paint(bin, nil, func(r uint32, _ interface{}) error {
+		m[r] = true
+		return nil
+	})
+	return m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestRemove(t *testing.T) {
+	a := New(8, 8)
+	sizes := [][2]int16{{4, 8}, {4, 4}, {4, 4}}
+	placed := map[uint32]placement{}
+	testPack(a, sizes, []uint32{0, 1, 2}, placed)
+	if a.BinCount() != 1 || a.Unused() != 0 {
+		t.Fatalf("packing: %d bins, %d unused", a.BinCount(), a.Unused())
+	}
+
+	// Leaf
+
+	if !a.Remove(0, 1) {
+		t.Errorf("remove: rectangle not found")
+	}
+	if a.Remove(0, 1) || a.Remove(1, 2) || a.Remove(-1, 2) {
+		t.Errorf("remove: rectangle found twice or in the wrong bin")
+	}
+	if p := painted(a, 0); p[1] || !p[0] || !p[2] {
+		t.Errorf("remove: painted %v", p)
+	}
+	if a.Unused() != 16 {
+		t.Errorf("remove: want 16 unused, got %d", a.Unused())
+	}
+
+	// Merge of two free halves
+
+	if !a.Remove(0, 2) {
+		t.Errorf("remove: rectangle not found")
+	}
+	f := a.bins[0].second
+	if f == nil || !f.empty() || f.w != 4 || f.h != 8 {
+		t.Errorf("remove: free halves not merged: %v", &a.bins[0])
+	}
+	if a.Unused() != 32 {
+		t.Errorf("remove: want 32 unused, got %d", a.Unused())
+	}
+
+	// Reuse of the freed space
+
+	sizes = append(sizes, [2]int16{4, 8})
+	testPack(a, sizes, []uint32{3}, placed)
+	if a.BinCount() != 1 || placed[3] != (placement{0, 4, 0}) {
+		t.Errorf("pack after remove: %d bins, put at %v", a.BinCount(), placed[3])
+	}
+	if a.Unused() != 0 {
+		t.Errorf("pack after remove: want 0 unused, got %d", a.Unused())
+	}
+
+	// Whole bin
+
+	a.Remove(0, 0)
+	a.Remove(0, 3)
+	if !a.bins[0].empty() || a.Unused() != 64 {
+		t.Errorf("bin not emptied: %v, %d unused", &a.bins[0], a.Unused())
+	}
+}
@@ -94,6 +94,33 @@ func (n *region) insert(rect uint32, bin int16, size SizeFn, put PutFn) *region
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// remove frees the leaf containing rect, and returns it. Regions whose two
+// halves are both free are merged back.
+func (n *region) remove(rect uint32) *region {
+	if n.first == nil {
+		if n.rect == rect {
+			n.rect = norect
+			return n
+		}
+		return nil
+	}
+
+	f := n.first.remove(rect)
+	if f == nil {
+		f = n.second.remove(rect)
+	}
+	if f != nil && n.first.empty() && n.second.empty() {
+		n.first, n.second = nil, nil
+	}
+	return f
+}
+
+func (n *region) empty() bool {
+	return n.first == nil && n.rect == norect
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 func (n *region) paint(bin int16, dest interface{}, paint PaintFn) error {
 	if n.rect != norect {
 		return paint(n.rect, dest)
@@ -31,6 +31,10 @@ static inline void FramebufferTexture(GLuint fbo, GLenum a, GLuint t, GLint l) {
 	glNamedFramebufferTexture(fbo, a, t, l);
 }
 
+static inline void FramebufferTextureLayer(GLuint fbo, GLenum a, GLuint t, GLint l, GLint layer) {
+	glNamedFramebufferTextureLayer(fbo, a, t, l, layer);
+}
+
 static inline void FramebufferRenderbuffer(GLuint fbo, GLenum a, GLuint t) {
 	glNamedFramebufferRenderbuffer(fbo, a, GL_RENDERBUFFER, t);
 }
@@ -108,6 +112,10 @@ func (fb Framebuffer) Texture(a FramebufferAttachment, t Texture2D, level int32)
 	C.FramebufferTexture(fb.object, C.GLenum(a), t.object, C.GLint(level))
 }
 
+func (fb Framebuffer) TextureLayer(a FramebufferAttachment, t TextureArray2D, level int32, layer int32) {
+	C.FramebufferTextureLayer(fb.object, C.GLenum(a), t.object, C.GLint(level), C.GLint(layer))
+}
+
 func (fb Framebuffer) Renderbuffer(a FramebufferAttachment, r Renderbuffer) {
 	C.FramebufferRenderbuffer(fb.object, C.GLenum(a), r.object)
 }
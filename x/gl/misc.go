@@ -12,6 +12,10 @@ static void Viewport(GLint x,  GLint y,  GLsizei width,  GLsizei height) {
 	glViewport(x, y, width, height);
 }
 
+static void Scissor(GLint x,  GLint y,  GLsizei width,  GLsizei height) {
+	glScissor(x, y, width, height);
+}
+
 static void DepthRange(GLdouble n, GLdouble f) {
 	glDepthRange(n, f);
 }
@@ -30,6 +34,14 @@ func Viewport(ox, oy, width, height int32) {
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// Scissor defines the rectangle, in window coordinates, outside of which
+// fragments are discarded. Note that you must also `Enable(ScissorTest)`.
+func Scissor(ox, oy, width, height int32) {
+	C.Scissor(C.GLint(ox), C.GLint(oy), C.GLsizei(width), C.GLsizei(height))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 // DepthRange specifies the mapping of depth values from normalized device
 // coordinates to window coordinates.
 func DepthRange(near, far float64) {
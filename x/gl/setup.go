@@ -46,6 +46,10 @@ func init() {
 }
 
 func setup() error {
+	if internal.Config.Headless {
+		return nil
+	}
+
 	var d C.int
 	if internal.Config.Debug {
 		d = 1
@@ -66,6 +70,9 @@ func init() {
 }
 
 func prerender() error {
+	if internal.Config.Headless {
+		return nil
+	}
 	DefaultFramebuffer.Bind(DrawFramebuffer)
 	if !noclear {
 		ClearColorBuffer(struct{ R, G, B, A float32 }{0, 0, 0, 0})
@@ -34,6 +34,18 @@ static inline void Texture2DSubImage(
 	glTextureSubImage2D(texture, level, xoffset, yoffset, width, height, format, type, pixels);
 }
 
+static inline void Texture2DGetImage(
+	GLuint texture,
+	GLint level,
+	GLenum format,
+	GLenum type,
+	GLsizei bufSize,
+	void *pixels
+) {
+	glPixelStorei(GL_PACK_ALIGNMENT, 1);
+	glGetTextureImage(texture, level, format, type, bufSize, pixels);
+}
+
 static inline void TextureGenerateMipmap(GLuint texture) {
 	glGenerateTextureMipmap(texture);
 }
@@ -81,6 +93,23 @@ func (t *Texture2D) SubImage(level int32, ox, oy int32, img image.Image) {
 	C.Texture2DSubImage(t.object, C.GLint(level), C.GLint(ox), C.GLint(oy), C.GLsizei(img.Bounds().Dx()), C.GLsizei(img.Bounds().Dy()), pf, pt, p)
 }
 
+// GetImage reads the content of a mipmap level of the texture into an image,
+// which must be of the same size. Note that the first row of the image
+// corresponds to the bottom of the texture.
+func (t *Texture2D) GetImage(level int32, img image.Image) {
+	p, pf, pt := pointerFormatAndTypeOf(img)
+	var n int
+	switch img := img.(type) {
+	case *image.RGBA:
+		n = len(img.Pix)
+	case *image.NRGBA:
+		n = len(img.Pix)
+	case *image.Paletted:
+		n = len(img.Pix)
+	}
+	C.Texture2DGetImage(t.object, C.GLint(level), pf, pt, C.GLsizei(n), p)
+}
+
 // GenerateMipmap generates mipmaps for the texture.
 func (t *Texture2D) GenerateMipmap() {
 	C.TextureGenerateMipmap(t.object)
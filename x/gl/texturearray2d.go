@@ -35,6 +35,10 @@ static inline void TextureArray2DSubImage(
 	glTextureSubImage3D(texture, level, xoffset, yoffset, zoffset, width, height, depth, format, type, pixels);
 }
 
+static inline void TextureArray2DCopy(GLuint dst, GLuint src, GLsizei width, GLsizei height, GLsizei depth) {
+	glCopyImageSubData(src, GL_TEXTURE_2D_ARRAY, 0, 0, 0, 0, dst, GL_TEXTURE_2D_ARRAY, 0, 0, 0, 0, width, height, depth);
+}
+
 static inline void TextureGenerateMipmap(GLuint texture) {
 	glGenerateTextureMipmap(texture);
 }
@@ -80,6 +84,12 @@ func (t *TextureArray2D) SubImage(level int32, ox, oy int32, index int32, img im
 	)
 }
 
+// CopyFrom copies the first count textures of another array (at mipmap level
+// 0). Both arrays must have compatible formats.
+func (t *TextureArray2D) CopyFrom(src *TextureArray2D, width, height int32, count int32) {
+	C.TextureArray2DCopy(t.object, src.object, C.GLsizei(width), C.GLsizei(height), C.GLsizei(count))
+}
+
 // GenerateMipmap generates mipmaps for the texture.
 func (t *TextureArray2D) GenerateMipmap() {
 	C.TextureGenerateMipmap(t.object)